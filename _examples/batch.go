@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"greqs"
+	"strconv"
+)
+
+func main() {
+	var reqs []*greqs.Request
+	for i := 1; i <= 10; i++ {
+		reqs = append(reqs, &greqs.Request{
+			Method: "GET",
+			Url:    "https://httpbin.org/get",
+			Params: greqs.S{"page": strconv.Itoa(i)},
+		})
+	}
+
+	results, err := greqs.Batch(context.Background(), reqs, &greqs.BatchOptions{Workers: 4})
+	if err != nil {
+		fmt.Printf("Error => %s\n", err)
+	}
+
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		fmt.Println(res.Index, res.Response.StatusCode)
+	}
+}
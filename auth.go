@@ -0,0 +1,284 @@
+package greqs
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Authenticator 认证器，每次尝试发送前为请求添加认证信息（请求头、查询参数、签名等）
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// Challenger 需要响应服务端质询的认证器（例如 Digest）。
+// 收到 401 时调用 Challenge，返回 true 表示已记录质询，请求会重新认证并再发送一次
+type Challenger interface {
+	Authenticator
+	Challenge(req *http.Request, resp *Response) (bool, error)
+}
+
+// AuthFunc 把函数转换为认证器，可以用来实现自定义签名
+type AuthFunc func(req *http.Request) error
+
+func (f AuthFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// BasicAuth HTTP Basic 认证
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a *BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerAuth Bearer 令牌认证
+type BearerAuth struct {
+	Token string
+}
+
+func (a *BearerAuth) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// APIKey 把密钥放在请求头或查询参数中
+type APIKey struct {
+	Name  string // 请求头或查询参数的名称，例如 X-API-Key
+	Value string
+	Query bool // 为 true 时放在查询参数中，否则放在请求头中
+}
+
+func (a *APIKey) Authenticate(req *http.Request) error {
+	if !a.Query {
+		req.Header.Set(a.Name, a.Value)
+		return nil
+	}
+	q := req.URL.Query()
+	q.Set(a.Name, a.Value)
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// DigestAuth HTTP Digest 认证（RFC 7616），支持 MD5、SHA-256、SHA-512-256 及其 -sess 变体和 qop=auth。
+// 第一次请求收到 401 质询后自动重新发送，之后的请求复用服务端的 nonce，可以被多个协程同时使用
+type DigestAuth struct {
+	Username string
+	Password string
+
+	mu        sync.Mutex
+	challenge map[string]string // 最近一次的质询参数
+	nc        int               // nonce 的使用次数
+}
+
+// NewDigestAuth 创建 Digest 认证器
+func NewDigestAuth(username, password string) *DigestAuth {
+	return &DigestAuth{Username: username, Password: password}
+}
+
+// Authenticate 还没有收到质询时不添加认证信息
+func (a *DigestAuth) Authenticate(req *http.Request) error {
+	a.mu.Lock()
+	if a.challenge == nil {
+		a.mu.Unlock()
+		return nil
+	}
+	a.nc++
+	c, nc := a.challenge, a.nc
+	a.mu.Unlock()
+
+	algorithm := c["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	qop := c["qop"]
+	if qop != "" {
+		if !containsToken(qop, "auth") {
+			return fmt.Errorf("不支持的 Digest qop: %s", qop)
+		}
+		qop = "auth"
+	}
+	realm, nonce, uri := c["realm"], c["nonce"], req.URL.RequestURI()
+	b := make([]byte, 8)
+	rand.Read(b)
+	cnonce, ncs := hex.EncodeToString(b), fmt.Sprintf("%08x", nc)
+
+	response, err := digestResponse(algorithm, a.Username, realm, a.Password, req.Method, uri, nonce, ncs, cnonce, qop)
+	if err != nil {
+		return err
+	}
+	fields := []string{
+		fmt.Sprintf("username=%q", a.Username),
+		fmt.Sprintf("realm=%q", realm),
+		fmt.Sprintf("nonce=%q", nonce),
+		fmt.Sprintf("uri=%q", uri),
+		"algorithm=" + algorithm,
+		fmt.Sprintf("response=%q", response),
+	}
+	if qop != "" {
+		fields = append(fields, "qop="+qop, "nc="+ncs, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	if opaque, ok := c["opaque"]; ok {
+		fields = append(fields, fmt.Sprintf("opaque=%q", opaque))
+	}
+	req.Header.Set("Authorization", "Digest "+strings.Join(fields, ", "))
+	return nil
+}
+
+// Challenge 记录 WWW-Authenticate 中的 Digest 质询
+func (a *DigestAuth) Challenge(req *http.Request, resp *Response) (bool, error) {
+	for _, value := range resp.Header.Values("WWW-Authenticate") {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		c := parseAuthParams(rest)
+		if c["nonce"] == "" {
+			return false, errors.New("Digest 质询缺少 nonce")
+		}
+		a.mu.Lock()
+		a.challenge, a.nc = c, 0
+		a.mu.Unlock()
+		return true, nil
+	}
+	return false, nil
+}
+
+// digestResponse 计算 Digest 的 response，qop 为空时使用 RFC 2069 的兼容算法
+func digestResponse(algorithm, username, realm, password, method, uri, nonce, nc, cnonce, qop string) (string, error) {
+	h, err := digestAlgorithm(algorithm)
+	if err != nil {
+		return "", err
+	}
+	sum := func(parts ...string) string {
+		d := h()
+		d.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+	ha1 := sum(username, realm, password)
+	if strings.HasSuffix(strings.ToLower(algorithm), "-sess") {
+		ha1 = sum(ha1, nonce, cnonce)
+	}
+	ha2 := sum(method, uri)
+	if qop == "" {
+		return sum(ha1, nonce, ha2), nil
+	}
+	return sum(ha1, nonce, nc, cnonce, qop, ha2), nil
+}
+
+// digestAlgorithm Digest 算法对应的摘要函数
+func digestAlgorithm(algorithm string) (func() hash.Hash, error) {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algorithm), "-sess")) {
+	case "MD5":
+		return md5.New, nil
+	case "SHA-256":
+		return sha256.New, nil
+	case "SHA-512-256":
+		return sha512.New512_256, nil
+	}
+	return nil, fmt.Errorf("不支持的 Digest 算法: %s", algorithm)
+}
+
+// containsToken 逗号分隔的列表中是否包含 token
+func containsToken(list, token string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAuthParams 解析 key=value, key="quoted, value" 形式的认证参数，键名转为小写
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return params
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimLeft(rest, " \t")
+
+		var val strings.Builder
+		if strings.HasPrefix(rest, `"`) {
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				val.WriteByte(rest[i])
+			}
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			val.WriteString(strings.TrimSpace(rest[:end]))
+			s = rest[end:]
+		}
+		params[key] = val.String()
+	}
+}
+
+// Auth 把认证器转换为中间件
+func Auth(a Authenticator) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			return authenticate(a, req, next)
+		}
+	}
+}
+
+// authenticate 认证后发送请求，认证器需要响应质询时遇到 401 会重新认证并再发送一次
+func authenticate(a Authenticator, req *http.Request, send Handler) (*Response, error) {
+	c, ok := a.(Challenger)
+	if ok {
+		if err := Rewindable(req); err != nil {
+			return nil, err
+		}
+	}
+	r := req.Clone(req.Context())
+	if err := a.Authenticate(r); err != nil {
+		return nil, err
+	}
+	resp, err := send(r)
+	if !ok || err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	again, err := c.Challenge(req, resp)
+	if err != nil {
+		resp.Close()
+		return nil, err
+	}
+	if !again {
+		return resp, nil
+	}
+	resp.Close()
+
+	r = req.Clone(req.Context())
+	if req.GetBody != nil {
+		if r.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.Authenticate(r); err != nil {
+		return nil, err
+	}
+	return send(r)
+}
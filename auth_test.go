@@ -0,0 +1,212 @@
+package greqs
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Digest 认证服务，nonce 固定，challenges 记录发出的质询次数
+func newDigestServer(t *testing.T, algorithm, user, pass string) (*httptest.Server, *int32) {
+	var challenges int32
+	const realm, nonce = "greqs", "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, rest, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		p := parseAuthParams(rest)
+		if scheme == "Digest" && p["nonce"] == nonce && p["username"] == user {
+			want, _ := digestResponse(algorithm, user, realm, pass, r.Method, p["uri"], nonce, p["nc"], p["cnonce"], p["qop"])
+			if p["response"] == want && p["uri"] == r.URL.RequestURI() && p["opaque"] == "5ccc069c" {
+				body, _ := io.ReadAll(r.Body)
+				w.Write([]byte("ok:" + string(body)))
+				return
+			}
+		}
+		atomic.AddInt32(&challenges, 1)
+		w.Header().Add("WWW-Authenticate", `Basic realm="greqs"`)
+		w.Header().Add("WWW-Authenticate", `Digest realm="greqs", qop="auth,auth-int", nonce="`+nonce+`", opaque="5ccc069c", algorithm=`+algorithm)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &challenges
+}
+
+func TestDigestResponse(t *testing.T) {
+	// RFC 7616 3.9.1 的示例
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+	for _, tt := range tests {
+		got, err := digestResponse(tt.algorithm, "Mufasa", "http-auth@example.org", "Circle of Life", "GET", "/dir/index.html",
+			"7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", "00000001", "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ", "auth")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.algorithm, got, tt.want)
+		}
+	}
+	if _, err := digestResponse("SHA-1", "", "", "", "", "", "", "", "", ""); err == nil {
+		t.Error("不支持的算法应该返回错误")
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	p := parseAuthParams(`realm="a, b", qop="auth,auth-int", nonce=abc , algorithm=MD5-sess, Opaque="x\"y"`)
+	want := map[string]string{"realm": "a, b", "qop": "auth,auth-int", "nonce": "abc", "algorithm": "MD5-sess", "opaque": `x"y`}
+	for key, val := range want {
+		if p[key] != val {
+			t.Errorf("%s = %q, want %q", key, p[key], val)
+		}
+	}
+}
+
+func TestWorker_DigestAuth(t *testing.T) {
+	for _, algorithm := range []string{"MD5", "SHA-256", "SHA-256-sess"} {
+		t.Run(algorithm, func(t *testing.T) {
+			srv, challenges := newDigestServer(t, algorithm, "greqs", "secret")
+
+			w := NewWorker("", 5*time.Second, nil, nil)
+			w.SetAuth(NewDigestAuth("greqs", "secret"))
+			resp, err := w.Post(srv.URL+"/login?from=test", nil, A{"id": 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != 200 || resp.Text() != `ok:{"id":1}` {
+				t.Fatalf("%d %q", resp.StatusCode, resp.Text())
+			}
+			// 之后的请求复用 nonce，不再收到质询
+			for i := 0; i < 3; i++ {
+				if resp, err := w.Get(srv.URL+"/profile", nil); err != nil || resp.StatusCode != 200 {
+					t.Fatal(resp, err)
+				}
+			}
+			if n := atomic.LoadInt32(challenges); n != 1 {
+				t.Errorf("质询 %d 次", n)
+			}
+		})
+	}
+}
+
+func TestDigestAuth_WrongPassword(t *testing.T) {
+	srv, challenges := newDigestServer(t, "MD5", "greqs", "secret")
+	resp, err := SendContext(context.Background(), "GET", srv.URL, &Options{Auth: NewDigestAuth("greqs", "wrong")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	// 只重新发送一次
+	if n := atomic.LoadInt32(challenges); n != 2 {
+		t.Errorf("质询 %d 次", n)
+	}
+}
+
+func TestAuthenticators(t *testing.T) {
+	srv := newEchoAuthServer(t)
+
+	tests := []struct {
+		name string
+		auth Authenticator
+		want string
+	}{
+		{"basic", &BasicAuth{Username: "greqs", Password: "secret"}, "Basic Z3JlcXM6c2VjcmV0|"},
+		{"bearer", &BearerAuth{Token: "t0k3n"}, "Bearer t0k3n|"},
+		{"api key header", &APIKey{Name: "X-API-Key", Value: "k"}, "|k|"},
+		{"api key query", &APIKey{Name: "api_key", Value: "k v", Query: true}, "||api_key=k+v&page=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Request{Method: "GET", Url: srv.URL, Params: S{"page": "1"}, Auth: tt.auth}
+			resp, err := r.Do()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Text(); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("got %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 返回 Authorization|X-API-Key|查询字符串|X-Signature
+func newEchoAuthServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Join([]string{
+			r.Header.Get("Authorization"), r.Header.Get("X-API-Key"), r.URL.RawQuery, r.Header.Get("X-Signature"),
+		}, "|")))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAuthFunc_Signing(t *testing.T) {
+	srv := newEchoAuthServer(t)
+
+	// 自定义 HMAC 签名：对 方法 + 路径 + 请求体 签名，重试时每次尝试都会重新签名
+	var attempts int32
+	sign := AuthFunc(func(req *http.Request) error {
+		atomic.AddInt32(&attempts, 1)
+		var body []byte
+		if req.GetBody != nil {
+			rc, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			body, _ = io.ReadAll(rc)
+		}
+		mac := hmac.New(sha256.New, []byte("key"))
+		mac.Write([]byte(req.Method + req.URL.Path))
+		mac.Write(body)
+		req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+		return nil
+	})
+
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.SetAuth(sign)
+	resp, err := w.Post(srv.URL+"/orders", nil, A{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte(`POST/orders{"id":1}`))
+	if want := "|||" + hex.EncodeToString(mac.Sum(nil)); resp.Text() != want {
+		t.Errorf("got %q, want %q", resp.Text(), want)
+	}
+
+	flaky, _ := newFlakyServer(2)
+	defer flaky.Close()
+	atomic.StoreInt32(&attempts, 0)
+	w.SetRetry(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	if _, err := w.Get(flaky.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Errorf("签名 %d 次", n)
+	}
+}
+
+func TestAuth_Middleware(t *testing.T) {
+	srv, _ := newDigestServer(t, "MD5", "greqs", "secret")
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(Auth(NewDigestAuth("greqs", "secret")))
+	resp, err := w.Get(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+}
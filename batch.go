@@ -0,0 +1,142 @@
+package greqs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// DefaultBatchWorkers 批量请求的默认并发数
+const DefaultBatchWorkers = 8
+
+// BatchOptions 批量请求配置
+type BatchOptions struct {
+	Workers  int     // 并发数，默认 DefaultBatchWorkers
+	FailFast bool    // 出现第一个错误时取消其余请求
+	Worker   *Worker // 使用 Worker 发送（代理、超时、重试、中间件等取 Worker 的配置），为空时使用 Request.DoContext
+}
+
+// Result 批量请求中一个请求的结果
+type Result struct {
+	Index    int       // 请求的序号（从 0 开始）
+	Request  *Request  // 请求
+	Response *Response // 响应
+	Err      error     // 错误
+}
+
+// Batch 并发执行 reqs，结果按请求的顺序返回。
+// 返回的错误为所有请求错误的合并，FailFast 模式下为第一个错误（其余请求的错误为 context.Canceled）。
+func Batch(ctx context.Context, reqs []*Request, opts *BatchOptions) ([]Result, error) {
+	feedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch := make(chan *Request)
+	go func() {
+		defer close(ch)
+		for _, r := range reqs {
+			select {
+			case ch <- r:
+			case <-feedCtx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make([]Result, len(reqs))
+	for i := range results {
+		results[i] = Result{Index: i, Request: reqs[i], Err: context.Canceled}
+	}
+	var errs []error
+	var firstErr error
+	for res := range BatchStream(ctx, ch, opts) {
+		results[res.Index] = res
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+			if firstErr == nil {
+				firstErr = res.Err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	if opts != nil && opts.FailFast {
+		return results, firstErr
+	}
+	return results, errors.Join(errs...)
+}
+
+// BatchStream 并发执行从 reqs 中读取的请求，结果在完成时发送到返回的通道，所有请求完成后通道关闭。
+// Result.Index 为请求从 reqs 中读出的顺序。FailFast 模式下第一个失败的结果先于被取消的请求的结果发送。
+// 不再读取通道时应取消 ctx，之后未发送的结果会被丢弃。
+func BatchStream(ctx context.Context, reqs <-chan *Request, opts *BatchOptions) <-chan Result {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultBatchWorkers
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	type job struct {
+		index int
+		req   *Request
+	}
+	jobs := make(chan job)
+	out := make(chan Result)
+
+	// 分发请求并编号
+	go func() {
+		defer close(jobs)
+		index := 0
+		for {
+			select {
+			case r, ok := <-reqs:
+				if !ok {
+					return
+				}
+				select {
+				case jobs <- job{index, r}:
+					index++
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				res := Result{Index: j.index, Request: j.req}
+				if err := ctx.Err(); err != nil {
+					res.Err = err
+				} else if opts.Worker != nil {
+					res.Response, res.Err = opts.Worker.Do(ctx, j.req)
+				} else {
+					res.Response, res.Err = j.req.DoContext(ctx)
+				}
+				// 先发送结果再取消，保证第一个错误先于 context.Canceled 被读到
+				select {
+				case out <- res:
+				case <-parent.Done():
+				}
+				if res.Err != nil && opts.FailFast {
+					cancel()
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,139 @@
+package greqs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 本地服务，返回查询参数 n，n 为 fail 时返回 500，同时记录最大并发数
+func newBatchServer() (*httptest.Server, *int32) {
+	var active, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if r.URL.Query().Get("n") == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(r.URL.Query().Get("n")))
+	}))
+	return srv, &peak
+}
+
+func TestBatch(t *testing.T) {
+	srv, peak := newBatchServer()
+	defer srv.Close()
+
+	var reqs []*Request
+	for i := 0; i < 20; i++ {
+		reqs = append(reqs, &Request{Method: "GET", Url: srv.URL, Params: S{"n": strconv.Itoa(i)}})
+	}
+	reqs = append(reqs, &Request{Method: "FETCH", Url: srv.URL})
+
+	results, err := Batch(context.Background(), reqs, &BatchOptions{Workers: 3})
+	if err == nil {
+		t.Error("expected joined error for invalid method")
+	}
+	for i, res := range results[:20] {
+		if res.Err != nil || res.Index != i || res.Response.Text() != strconv.Itoa(i) {
+			t.Errorf("result %d = %+v", i, res)
+		}
+	}
+	if results[20].Err == nil {
+		t.Error("invalid method should fail")
+	}
+	if *peak > 3 {
+		t.Errorf("peak concurrency = %d, want <= 3", *peak)
+	}
+}
+
+func TestBatch_FailFast(t *testing.T) {
+	srv, _ := newBatchServer()
+	defer srv.Close()
+
+	errFail := errors.New("fail")
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.Use(func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			resp, err := next(req)
+			if err == nil && resp.StatusCode >= 500 {
+				return resp, errFail
+			}
+			return resp, err
+		}
+	})
+
+	reqs := []*Request{{Method: "GET", Url: srv.URL, Params: S{"n": "fail"}}}
+	for i := 0; i < 50; i++ {
+		reqs = append(reqs, &Request{Method: "GET", Url: srv.URL, Params: S{"n": strconv.Itoa(i)}})
+	}
+	results, err := Batch(context.Background(), reqs, &BatchOptions{Workers: 2, FailFast: true, Worker: worker})
+	if !errors.Is(err, errFail) {
+		t.Errorf("err = %v, want fail", err)
+	}
+	if len(results) != len(reqs) || !errors.Is(results[len(results)-1].Err, context.Canceled) {
+		t.Errorf("last result = %+v, want canceled", results[len(results)-1])
+	}
+}
+
+func TestBatchStream(t *testing.T) {
+	srv, _ := newBatchServer()
+	defer srv.Close()
+
+	ch := make(chan *Request)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 5; i++ {
+			ch <- &Request{Method: "GET", Url: srv.URL, Params: S{"n": strconv.Itoa(i)}}
+		}
+	}()
+
+	seen := map[int]bool{}
+	for res := range BatchStream(context.Background(), ch, nil) {
+		if res.Err != nil || res.Response.Text() != strconv.Itoa(res.Index) {
+			t.Errorf("result = %+v", res)
+		}
+		seen[res.Index] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("seen = %v", seen)
+	}
+}
+
+func TestBatchStream_Cancel(t *testing.T) {
+	srv, _ := newBatchServer()
+	defer srv.Close()
+
+	ch := make(chan *Request, 10)
+	for i := 0; i < 10; i++ {
+		ch <- &Request{Method: "GET", Url: srv.URL, Params: S{"n": strconv.Itoa(i)}}
+	}
+	close(ch)
+
+	// 取消后不再读取，工作协程不会阻塞在发送结果上，通道随之关闭
+	ctx, cancel := context.WithCancel(context.Background())
+	out := BatchStream(ctx, ch, &BatchOptions{Workers: 4})
+	<-out
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+	n := 0
+	for range out {
+		n++
+	}
+	if n != 0 {
+		t.Errorf("取消后仍收到 %d 个结果", n)
+	}
+}
@@ -0,0 +1,77 @@
+package greqs
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	_url "net/url"
+	"strings"
+)
+
+// Body 请求体，按 Reader、Raw、Multipart、JSON、XML、Data、Form 的顺序取第一个不为空的字段
+type Body struct {
+	Multipart   *Multipart // 多部分表单（文件上传）
+	Data        A          // JSON 请求体（map 形式）
+	Form        S          // 请求表单
+	JSON        any        // 任意可以编码为 JSON 的值（结构体、切片等）
+	XML         any        // 任意可以编码为 XML 的值
+	Raw         []byte     // 原始请求体
+	Reader      io.Reader  // 流式请求体
+	ContentType string     // 请求体类型，为空时根据字段推断，Raw 和 Reader 默认为 application/octet-stream
+}
+
+// IsEmpty 是否没有请求体
+func (b *Body) IsEmpty() bool {
+	return b == nil || (b.Reader == nil && b.Raw == nil && b.Multipart == nil && b.JSON == nil && b.XML == nil && b.Data == nil && b.Form == nil)
+}
+
+// Encode 编码请求体，返回请求体和请求体类型，没有请求体时返回 nil
+func (b *Body) Encode() (io.Reader, string, error) {
+	if b.IsEmpty() {
+		return nil, "", nil
+	}
+
+	var (
+		body        io.Reader
+		contentType string
+	)
+	switch {
+	case b.Reader != nil:
+		body, contentType = b.Reader, "application/octet-stream"
+	case b.Raw != nil:
+		body, contentType = bytes.NewReader(b.Raw), "application/octet-stream"
+	case b.Multipart != nil:
+		body, contentType = b.Multipart.Reader()
+	case b.JSON != nil:
+		data, err := json.Marshal(b.JSON)
+		if err != nil {
+			return nil, "", fmt.Errorf("编码JSON失败: %w", err)
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	case b.XML != nil:
+		data, err := xml.Marshal(b.XML)
+		if err != nil {
+			return nil, "", fmt.Errorf("编码XML失败: %w", err)
+		}
+		body, contentType = bytes.NewReader(append([]byte(xml.Header), data...)), "application/xml"
+	case b.Data != nil:
+		data, err := json.Marshal(b.Data)
+		if err != nil {
+			return nil, "", fmt.Errorf("编码JSON失败: %w", err)
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	default:
+		val := _url.Values{}
+		for k, v := range b.Form {
+			val.Set(k, v)
+		}
+		body, contentType = strings.NewReader(val.Encode()), "application/x-www-form-urlencoded"
+	}
+
+	if b.ContentType != "" {
+		contentType = b.ContentType
+	}
+	return body, contentType, nil
+}
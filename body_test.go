@@ -0,0 +1,66 @@
+package greqs
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequest_Bodies(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	type item struct {
+		Name string `json:"name" xml:"name"`
+	}
+	cases := []struct {
+		name        string
+		req         *Request
+		contentType string
+		body        string
+	}{
+		{"json array", &Request{JSON: []item{{"a"}, {"b"}}}, "application/json", `[{"name":"a"},{"name":"b"}]`},
+		{"xml", &Request{XML: item{"a"}}, "application/xml", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<item><name>a</name></item>`},
+		{"raw", &Request{Body: []byte("raw"), ContentType: "text/plain"}, "text/plain", "raw"},
+		{"reader", &Request{Reader: strings.NewReader("stream")}, "application/octet-stream", "stream"},
+	}
+	for _, c := range cases {
+		c.req.Method, c.req.Url = "PUT", srv.URL
+		resp, err := c.req.Do()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := resp.Header.Get("X-Content-Type"); got != c.contentType {
+			t.Errorf("%s: content type = %s, want %s", c.name, got, c.contentType)
+		}
+		if resp.Text() != c.body {
+			t.Errorf("%s: body = %q, want %q", c.name, resp.Text(), c.body)
+		}
+	}
+}
+
+func TestMakeBodyRequest_Error(t *testing.T) {
+	if _, err := MakeBodyRequest("POST", "http://localhost", nil, &Body{JSON: make(chan int)}); err == nil {
+		t.Error("expected JSON encode error")
+	}
+	if _, err := SendPostRequest("http://localhost", &Options{Data: A{"ch": make(chan int)}}); err == nil {
+		t.Error("expected JSON encode error")
+	}
+}
+
+func TestWorker_SendBody(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetRetry(&RetryPolicy{MaxAttempts: 2})
+	resp, err := worker.SendBody(context.Background(), "POST", srv.URL, nil, &Body{Reader: io.NopCloser(strings.NewReader("stream"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "stream" {
+		t.Errorf("body = %q", resp.Text())
+	}
+}
@@ -0,0 +1,411 @@
+package greqs
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 没有明确过期时间时可以按启发式规则缓存的状态码（RFC 9110 15.1）
+var heuristicStatus = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// CacheEntry 缓存的响应
+type CacheEntry struct {
+	Url          string            `json:"url"`
+	StatusCode   int               `json:"status_code"`
+	Header       http.Header       `json:"header"`
+	Body         []byte            `json:"body"`
+	Vary         map[string]string `json:"vary,omitempty"` // 响应 Vary 指定的请求头在请求中的值
+	RequestTime  time.Time         `json:"request_time"`
+	ResponseTime time.Time         `json:"response_time"`
+}
+
+// CacheStore 缓存存储，实现需要可以被多个协程同时使用
+type CacheStore interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// MemoryCache 内存中的 LRU 缓存
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryCache 创建最多保存 capacity 个响应的内存缓存，capacity 不大于 0 时不限制
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{capacity: capacity, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+func (c *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		return el.Value.(*memoryItem).entry, true
+	}
+	return nil, false
+}
+
+func (c *MemoryCache) Set(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*memoryItem).entry = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&memoryItem{key: key, entry: entry})
+	for c.capacity > 0 && c.ll.Len() > c.capacity {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*memoryItem).key)
+	}
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Len 缓存的响应数量
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// DiskCache 保存在目录中的缓存，每个响应一个 JSON 文件，进程重启后仍然有效
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache 创建磁盘缓存，目录不存在时自动创建
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// path 缓存文件的路径
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *DiskCache) Get(key string) (*CacheEntry, bool) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Set 先写入临时文件再重命名，避免读到写了一半的文件
+func (c *DiskCache) Set(key string, entry *CacheEntry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	f, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), c.path(key)); err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+func (c *DiskCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+// FromCache 响应是否来自缓存（包括重新验证后仍然有效的缓存）
+func (r *Response) FromCache() bool {
+	return r.cached
+}
+
+// Cache HTTP 缓存中间件（RFC 9111，私有缓存），只缓存 GET 请求的非流式响应。
+// 支持 Cache-Control、Expires、Age、Vary，过期后通过 ETag / Last-Modified 发送条件请求重新验证，
+// 其他方法的请求成功后会使同一网址的缓存失效
+func Cache(store CacheStore) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			return cacheRoundTrip(store, req, next)
+		}
+	}
+}
+
+// cacheRoundTrip 查找缓存，必要时重新验证或请求源站
+func cacheRoundTrip(store CacheStore, req *http.Request, next Handler) (*Response, error) {
+	key := req.URL.String()
+	if req.Method != http.MethodGet {
+		resp, err := next(req)
+		if err == nil && req.Method != http.MethodHead && req.Method != http.MethodOptions && resp.StatusCode < 400 {
+			store.Delete(key)
+		}
+		return resp, err
+	}
+	// 调用方自己发起的条件请求和 Range 请求不经过缓存
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" || req.Header.Get("Range") != "" {
+		return next(req)
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if _, ok := reqCC["no-cache"]; !ok && len(req.Header.Values("Cache-Control")) == 0 && strings.Contains(req.Header.Get("Pragma"), "no-cache") {
+		reqCC["no-cache"] = ""
+	}
+	if _, ok := reqCC["no-store"]; ok {
+		return next(req)
+	}
+
+	entry, ok := store.Get(key)
+	if ok && !entry.matchVary(req) {
+		entry, ok = nil, false
+	}
+	if ok && entry.usable(reqCC) {
+		return entry.response(req), nil
+	}
+	if _, only := reqCC["only-if-cached"]; only {
+		return newResponse(req, http.StatusGatewayTimeout, http.Header{}, nil), nil
+	}
+
+	// 有缓存但需要重新验证时发送条件请求
+	conditional := req
+	if ok {
+		conditional = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			conditional.Header.Set("If-None-Match", etag)
+		}
+		if lm := entry.Header.Get("Last-Modified"); lm != "" {
+			conditional.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	requestTime := time.Now()
+	resp, err := next(conditional)
+	if err != nil {
+		return resp, err
+	}
+	responseTime := time.Now()
+
+	if ok && resp.StatusCode == http.StatusNotModified {
+		// 用 304 的响应头更新缓存
+		resp.Close()
+		updated := *entry
+		updated.Header = entry.Header.Clone()
+		for k, v := range resp.Header {
+			if k != "Content-Length" {
+				updated.Header[k] = v
+			}
+		}
+		updated.RequestTime, updated.ResponseTime = requestTime, responseTime
+		store.Set(key, &updated)
+		return updated.response(req), nil
+	}
+
+	// 流式或不可存储的响应不写入缓存，已有的条目保留（只有不安全方法的请求才会使缓存失效）
+	if resp.IsStream() || !storable(resp) {
+		return resp, nil
+	}
+	entry = &CacheEntry{
+		Url:          key,
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         bytes.Clone(resp.Body),
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	}
+	for _, name := range varyHeaders(resp.Header) {
+		if entry.Vary == nil {
+			entry.Vary = map[string]string{}
+		}
+		entry.Vary[name] = req.Header.Get(name)
+	}
+	store.Set(key, entry)
+	return resp, nil
+}
+
+// storable 响应能否缓存
+func storable(resp *Response) bool {
+	cc := parseCacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	for _, name := range varyHeaders(resp.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+	_, maxAge := cc["max-age"]
+	explicit := maxAge || resp.Header.Get("Expires") != ""
+	if !explicit && !heuristicStatus[resp.StatusCode] {
+		return false
+	}
+	// 没有过期时间也没有验证器的响应存下来也用不上
+	return explicit || resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// matchVary 请求中 Vary 指定的请求头与缓存时是否一致
+func (e *CacheEntry) matchVary(req *http.Request) bool {
+	for name, val := range e.Vary {
+		if req.Header.Get(name) != val {
+			return false
+		}
+	}
+	return true
+}
+
+// usable 不重新验证能否直接使用
+func (e *CacheEntry) usable(reqCC map[string]string) bool {
+	cc := parseCacheControl(e.Header)
+	if _, ok := cc["no-cache"]; ok {
+		return false
+	}
+	if _, ok := reqCC["no-cache"]; ok {
+		return false
+	}
+	age, lifetime := e.age(), e.lifetime(cc)
+	if v, ok := reqCC["max-age"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil && age > time.Duration(secs)*time.Second {
+			return false
+		}
+	}
+	if v, ok := reqCC["min-fresh"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil {
+			age += time.Duration(secs) * time.Second
+		}
+	}
+	if age < lifetime {
+		return true
+	}
+	// 请求允许使用过期的响应，响应要求必须重新验证时除外
+	if v, ok := reqCC["max-stale"]; ok {
+		if _, must := cc["must-revalidate"]; must {
+			return false
+		}
+		if v == "" {
+			return true
+		}
+		if secs, err := strconv.Atoi(v); err == nil {
+			return age-lifetime <= time.Duration(secs)*time.Second
+		}
+	}
+	return false
+}
+
+// lifetime 新鲜度有效期（RFC 9111 4.2.1）
+func (e *CacheEntry) lifetime(cc map[string]string) time.Duration {
+	if v, ok := cc["max-age"]; ok {
+		secs, err := strconv.Atoi(v)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	date := e.date()
+	if v := e.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0 // 无效的 Expires 视为已过期
+		}
+		return expires.Sub(date)
+	}
+	// 启发式：Last-Modified 距今时间的 10%，最多一天
+	if lm, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && heuristicStatus[e.StatusCode] {
+		return min(date.Sub(lm)/10, 24*time.Hour)
+	}
+	return 0
+}
+
+// age 当前年龄（RFC 9111 4.2.3）
+func (e *CacheEntry) age() time.Duration {
+	apparent := max(e.ResponseTime.Sub(e.date()), 0)
+	var ageValue time.Duration
+	if secs, err := strconv.Atoi(e.Header.Get("Age")); err == nil && secs > 0 {
+		ageValue = time.Duration(secs) * time.Second
+	}
+	corrected := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	return max(apparent, corrected) + time.Since(e.ResponseTime)
+}
+
+// date 响应的 Date，没有时使用收到响应的时间
+func (e *CacheEntry) date() time.Time {
+	if t, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return t
+	}
+	return e.ResponseTime
+}
+
+// response 转换为响应
+func (e *CacheEntry) response(req *http.Request) *Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(e.age()/time.Second)))
+	resp := newResponse(req, e.StatusCode, header, e.Body)
+	resp.cached = true
+	return resp
+}
+
+// varyHeaders 响应 Vary 中的请求头名称
+func varyHeaders(h http.Header) []string {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// parseCacheControl 解析 Cache-Control，指令名转为小写，没有值的指令对应空字符串
+func parseCacheControl(h http.Header) map[string]string {
+	cc := map[string]string{}
+	for _, v := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(v, ",") {
+			name, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				cc[name] = strings.Trim(strings.TrimSpace(val), `"`)
+			}
+		}
+	}
+	return cc
+}
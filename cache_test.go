@@ -0,0 +1,276 @@
+package greqs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 缓存测试服务，/etag 与 /modified 支持条件请求，hits 记录每个路径收到的请求数
+type cacheServer struct {
+	*httptest.Server
+	hits map[string]*int32
+}
+
+func newCacheServer(t *testing.T) *cacheServer {
+	lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	routes := map[string]func(w http.ResponseWriter, r *http.Request){
+		"/fresh": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+		},
+		"/old": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Age", "120")
+		},
+		"/etag": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.Header().Set("X-Revalidated", "yes")
+				w.WriteHeader(http.StatusNotModified)
+			}
+		},
+		"/modified": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=0")
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+			}
+		},
+		"/expired": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Expires", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+		},
+		"/expires": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		},
+		"/no-store": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		},
+		"/vary": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+		},
+	}
+	s := &cacheServer{hits: map[string]*int32{}}
+	mux := http.NewServeMux()
+	for path, handle := range routes {
+		s.hits[path] = new(int32)
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(s.hits[path], 1)
+			if r.Method == http.MethodGet {
+				handle(w, r)
+			}
+			w.Write([]byte(r.Method + " " + r.Header.Get("Accept-Language") + " " + strconv.Itoa(int(n))))
+		})
+	}
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *cacheServer) count(path string) int32 {
+	return atomic.LoadInt32(s.hits[path])
+}
+
+func TestCache_Freshness(t *testing.T) {
+	srv := newCacheServer(t)
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(Cache(NewMemoryCache(100)))
+
+	tests := []struct {
+		path   string
+		hits   int32 // 请求两次后源站收到的请求数
+		cached bool  // 第二次是否来自缓存
+	}{
+		{"/fresh", 1, true},
+		{"/expires", 1, true},
+		{"/old", 2, false},     // Age 超过 max-age
+		{"/expired", 2, false}, // Expires 早于 Date
+		{"/no-store", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			first, err := w.Get(srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first.FromCache() {
+				t.Fatal("第一次请求不应该来自缓存")
+			}
+			second, err := w.Get(srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if second.FromCache() != tt.cached || srv.count(tt.path) != tt.hits {
+				t.Fatalf("cached = %v, hits = %d", second.FromCache(), srv.count(tt.path))
+			}
+			if tt.cached {
+				if second.Text() != first.Text() || second.StatusCode != 200 || second.Header.Get("Age") == "" {
+					t.Fatalf("缓存的响应 %d %q %v", second.StatusCode, second.Text(), second.Header)
+				}
+			}
+		})
+	}
+}
+
+func TestCache_Revalidate(t *testing.T) {
+	srv := newCacheServer(t)
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(Cache(NewMemoryCache(100)))
+
+	for _, path := range []string{"/etag", "/modified"} {
+		first, err := w.Get(srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			resp, err := w.Get(srv.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 源站返回 304，使用缓存的响应体
+			if !resp.FromCache() || resp.StatusCode != 200 || resp.Text() != first.Text() {
+				t.Fatalf("%s: %v %d %q", path, resp.FromCache(), resp.StatusCode, resp.Text())
+			}
+		}
+		if n := srv.count(path); n != 3 {
+			t.Fatalf("%s: hits = %d", path, n)
+		}
+	}
+
+	// 304 的响应头会更新到缓存中
+	resp, _ := w.Get(srv.URL+"/etag", nil)
+	if resp.Header.Get("X-Revalidated") != "yes" {
+		t.Errorf("响应头 %v", resp.Header)
+	}
+}
+
+func TestCache_RequestDirectives(t *testing.T) {
+	srv := newCacheServer(t)
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(Cache(NewMemoryCache(100)))
+
+	resp, _ := w.Get(srv.URL+"/fresh", S{"Cache-Control": "only-if-cached"})
+	if resp.StatusCode != http.StatusGatewayTimeout || srv.count("/fresh") != 0 {
+		t.Fatalf("only-if-cached: %d", resp.StatusCode)
+	}
+	w.Get(srv.URL+"/fresh", nil)
+	if resp, _ := w.Get(srv.URL+"/fresh", S{"Cache-Control": "only-if-cached"}); !resp.FromCache() {
+		t.Fatal("only-if-cached 应该使用缓存")
+	}
+	// no-cache 和 max-age=0 都要求重新请求（没有验证器时拿到新的响应）
+	for _, cc := range []string{"no-cache", "max-age=0"} {
+		if resp, _ := w.Get(srv.URL+"/fresh", S{"Cache-Control": cc}); resp.FromCache() {
+			t.Fatalf("%s 不应该使用缓存", cc)
+		}
+	}
+	if resp, _ := w.Get(srv.URL+"/fresh", S{"Pragma": "no-cache"}); resp.FromCache() {
+		t.Fatal("Pragma: no-cache 不应该使用缓存")
+	}
+	if n := srv.count("/fresh"); n != 4 {
+		t.Fatalf("hits = %d", n)
+	}
+	// 过期的响应在 max-stale 内可以使用
+	w.Get(srv.URL+"/old", nil)
+	if resp, _ := w.Get(srv.URL+"/old", S{"Cache-Control": "max-stale=3600"}); !resp.FromCache() {
+		t.Fatal("max-stale 应该使用缓存")
+	}
+}
+
+func TestCache_VaryAndInvalidate(t *testing.T) {
+	srv := newCacheServer(t)
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(Cache(NewMemoryCache(100)))
+
+	w.Get(srv.URL+"/vary", S{"Accept-Language": "zh"})
+	if resp, _ := w.Get(srv.URL+"/vary", S{"Accept-Language": "zh"}); !resp.FromCache() {
+		t.Fatal("相同的 Accept-Language 应该使用缓存")
+	}
+	if resp, _ := w.Get(srv.URL+"/vary", S{"Accept-Language": "en"}); resp.FromCache() || resp.Text() != "GET en 2" {
+		t.Fatalf("不同的 Accept-Language 不应该使用缓存: %q", resp.Text())
+	}
+
+	// 成功的 POST 使缓存失效
+	w.Get(srv.URL+"/fresh", nil)
+	if resp, _ := w.Post(srv.URL+"/fresh", nil, A{"a": 1}); resp.FromCache() {
+		t.Fatal("POST 不应该使用缓存")
+	}
+	if resp, _ := w.Get(srv.URL+"/fresh", nil); resp.FromCache() || resp.Text() != "GET  3" {
+		t.Fatalf("POST 之后应该重新请求: %q", resp.Text())
+	}
+}
+
+func TestCache_StreamKeepsEntry(t *testing.T) {
+	srv := newCacheServer(t)
+	store := NewMemoryCache(100)
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(Cache(store))
+
+	// 流式 GET 不写入缓存，也不删除已有的条目
+	w.Get(srv.URL+"/old", nil)
+	req, _ := MakeGetRequest(srv.URL+"/old", nil)
+	resp, err := w.GoStream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Close()
+	if _, ok := store.Get(srv.URL + "/old"); !ok {
+		t.Error("流式请求删除了缓存")
+	}
+}
+
+func TestMemoryCache_LRU(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", &CacheEntry{Url: "a"})
+	c.Set("b", &CacheEntry{Url: "b"})
+	c.Get("a") // a 最近使用过，淘汰 b
+	c.Set("c", &CacheEntry{Url: "c"})
+	if _, ok := c.Get("b"); ok {
+		t.Error("b 应该被淘汰")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s 不应该被淘汰", key)
+		}
+	}
+	c.Delete("a")
+	if c.Len() != 1 {
+		t.Errorf("Len = %d", c.Len())
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	srv := newCacheServer(t)
+	dir := t.TempDir() + "/cache"
+
+	store, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(Cache(store))
+	first, _ := w.Get(srv.URL+"/fresh", nil)
+
+	// 新的 Worker 和存储读取同一个目录，模拟进程重启
+	store, _ = NewDiskCache(dir)
+	w = NewWorker("", 5*time.Second, nil, nil)
+	w.Use(Cache(store))
+	resp, err := w.Get(srv.URL+"/fresh", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.FromCache() || resp.Text() != first.Text() || resp.Header.Get("Cache-Control") != "max-age=60" {
+		t.Fatalf("%v %q", resp.FromCache(), resp.Text())
+	}
+	if n := srv.count("/fresh"); n != 1 {
+		t.Fatalf("hits = %d", n)
+	}
+	store.Delete(srv.URL + "/fresh")
+	if _, ok := store.Get(srv.URL + "/fresh"); ok {
+		t.Fatal("删除后不应该存在")
+	}
+}
@@ -0,0 +1,258 @@
+package greqs
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 本地 SOCKS5 代理（RFC 1928 / RFC 1929），只支持 CONNECT，user 不为空时要求用户名密码认证
+type socksServer struct {
+	ln    net.Listener
+	user  string
+	pass  string
+	conns int32
+	hosts chan string // 收到的目标地址
+}
+
+func newSocksServer(t *testing.T, user, pass string) *socksServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socksServer{ln: ln, user: user, pass: pass, hosts: make(chan string, 16)}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&s.conns, 1)
+			go s.serve(c)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *socksServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+
+	// 协商认证方式
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return
+	}
+	methods := make([]byte, head[1])
+	io.ReadFull(r, methods)
+	if s.user == "" {
+		c.Write([]byte{5, 0})
+	} else {
+		c.Write([]byte{5, 2})
+		ver, _ := r.ReadByte()
+		ulen, _ := r.ReadByte()
+		user := make([]byte, ulen)
+		io.ReadFull(r, user)
+		plen, _ := r.ReadByte()
+		pass := make([]byte, plen)
+		io.ReadFull(r, pass)
+		if ver != 1 || string(user) != s.user || string(pass) != s.pass {
+			c.Write([]byte{1, 1})
+			return
+		}
+		c.Write([]byte{1, 0})
+	}
+
+	// CONNECT 请求
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil || req[1] != 1 {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 3:
+		n, _ := r.ReadByte()
+		name := make([]byte, n)
+		io.ReadFull(r, name)
+		host = string(name)
+	default:
+		return
+	}
+	portBytes := make([]byte, 2)
+	io.ReadFull(r, portBytes)
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(portBytes))))
+	s.hosts <- addr
+
+	dial := addr
+	if host == "greqs.test" {
+		dial = net.JoinHostPort("127.0.0.1", strconv.Itoa(int(binary.BigEndian.Uint16(portBytes))))
+	}
+	target, err := net.Dial("tcp", dial)
+	if err != nil {
+		c.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(target, r)
+	io.Copy(c, target)
+}
+
+func (s *socksServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func TestWorker_Socks5(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+	socks := newSocksServer(t, "greqs", "secret")
+
+	worker := NewWorker("socks5://greqs:secret@"+socks.addr(), 5*time.Second, nil, nil)
+	resp, err := worker.Put(srv.URL, nil, A{"via": "socks5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != `{"via":"socks5"}` || atomic.LoadInt32(&socks.conns) != 1 {
+		t.Errorf("body = %s, conns = %d", resp.Text(), socks.conns)
+	}
+
+	// socks5h 把主机名交给代理解析
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	worker.SetProxy("socks5h://greqs:secret@" + socks.addr())
+	resp, err = worker.Get("http://greqs.test:"+port+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-socks.hosts
+	if host := <-socks.hosts; host != "greqs.test:"+port {
+		t.Errorf("socks5h target = %s", host)
+	}
+
+	// 密码错误
+	worker.SetProxy("socks5://greqs:wrong@" + socks.addr())
+	if _, err := worker.Get(srv.URL, nil); err == nil {
+		t.Error("expected auth error")
+	}
+}
+
+func TestWorker_HTTPProxyAuth(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := "Basic " + base64.StdEncoding.EncodeToString([]byte("greqs:secret"))
+		if r.Header.Get("Proxy-Authorization") != want {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		w.Write([]byte("proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	host := strings.TrimPrefix(proxy.URL, "http://")
+	worker := NewWorker("greqs:secret@"+host, 5*time.Second, nil, nil)
+	resp, err := worker.Get("http://example.com/a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "proxied http://example.com/a" {
+		t.Errorf("body = %s", resp.Text())
+	}
+}
+
+func TestWorker_ProxyConnectHeaders(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tls ok"))
+	}))
+	defer target.Close()
+
+	var connectHeader string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		connectHeader = r.Header.Get("X-Tunnel-Token")
+		dst, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, _ := w.(http.Hijacker).Hijack()
+		go io.Copy(dst, buf)
+		io.Copy(conn, dst)
+		conn.Close()
+		dst.Close()
+	}))
+	defer proxy.Close()
+
+	tlsConfig := target.Client().Transport.(*http.Transport).TLSClientConfig
+	worker := NewWorker(proxy.URL, 5*time.Second, nil, func(cli *http.Client) {
+		cli.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+	})
+	worker.SetProxyConnectHeaders(S{"X-Tunnel-Token": "abc"})
+	resp, err := worker.Get(target.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "tls ok" || connectHeader != "abc" {
+		t.Errorf("body = %s, header = %q", resp.Text(), connectHeader)
+	}
+}
+
+func TestParseProxy(t *testing.T) {
+	for _, proxy := range []string{"127.0.0.1:7890", "http://u:p@h:1", "socks5://h:1080", "socks5h://h:1080"} {
+		if _, err := ParseProxy(proxy); err != nil {
+			t.Errorf("%s: %v", proxy, err)
+		}
+	}
+	for _, proxy := range []string{"ftp://h:21", "http://", "http://h:%zz"} {
+		if _, err := ParseProxy(proxy); err == nil {
+			t.Errorf("%s: expected error", proxy)
+		}
+	}
+
+	// 无效代理不再 panic，而是在请求时返回错误
+	if _, err := Send("GET", "http://example.com", &Options{Proxy: "ftp://h:21"}); err == nil {
+		t.Error("expected proxy error")
+	}
+	if _, err := GetClient("ftp://h:21", 0).Get("http://example.com"); err == nil {
+		t.Error("expected proxy error from GetClient")
+	}
+	if _, err := NewWorker("ftp://h:21", 0, nil, nil).Get("http://example.com", nil); err == nil {
+		t.Error("expected proxy error from Worker")
+	}
+}
+
+func TestSharedClient(t *testing.T) {
+	// 内容相同的 TLS 配置共用 Transport，与指针无关
+	a, _ := sharedClient("", time.Second, &TLSOptions{ServerName: "a.example"})
+	b, _ := sharedClient("", 2*time.Second, &TLSOptions{ServerName: "a.example"})
+	c, _ := sharedClient("", time.Second, &TLSOptions{ServerName: "b.example"})
+	if a.Transport != b.Transport || a == b || a.Transport == c.Transport {
+		t.Error("Transport 应该按 TLS 配置的内容共享")
+	}
+
+	// 缓存的 Transport 数量有上限
+	for i := 0; i < maxSharedTransports*2; i++ {
+		sharedClient("", 0, &TLSOptions{ServerName: strconv.Itoa(i) + ".example"})
+	}
+	sharedMu.Lock()
+	n := sharedList.Len()
+	sharedMu.Unlock()
+	if n != maxSharedTransports {
+		t.Errorf("缓存了 %d 个 Transport", n)
+	}
+}
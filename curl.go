@@ -0,0 +1,581 @@
+package greqs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	_url "net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ToCurl 转换为等价的 curl 命令（方法、网址、请求头、请求体、代理、超时、TLS 文件配置）。
+// Basic、Bearer 和 API Key 认证会写入命令，其他认证器（Digest、OAuth2 等）需要交互，不会出现在命令中；
+// 流式请求体和来自 io.Reader 的上传文件无法转换，返回错误
+func (r *Request) ToCurl() (string, error) {
+	method, err := CheckMethod(r.Method)
+	if err != nil {
+		return "", err
+	}
+	url := r.Url
+	if r.Params != nil {
+		url = MakeUrl(url, r.Params)
+	}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	SetHeaders(req, r.Headers)
+
+	args := []string{"curl"}
+	switch a := r.Auth.(type) {
+	case *BasicAuth:
+		args = append(args, "-u", a.Username+":"+a.Password)
+	case *BearerAuth, *APIKey:
+		if err := a.Authenticate(req); err != nil {
+			return "", err
+		}
+	}
+
+	// 请求体，multipart 的 Content-Type 由 curl 生成（包含分隔符）
+	body := r.Options().body()
+	var data []string
+	switch {
+	case body.Reader != nil:
+		return "", errors.New("流式请求体无法转换为 curl 命令")
+	case body.Raw == nil && body.Multipart != nil:
+		for _, field := range body.Multipart.fields {
+			data = append(data, "--form-string", field[0]+"="+field[1])
+		}
+		for _, f := range body.Multipart.files {
+			if f.Reader != nil || f.Data != nil {
+				return "", fmt.Errorf("不在磁盘上的文件 %s 无法转换为 curl 命令", f.Field)
+			}
+			form := f.Field + "=@" + f.Path
+			if f.Filename != "" {
+				form += ";filename=" + f.Filename
+			}
+			if f.ContentType != "" {
+				form += ";type=" + f.ContentType
+			}
+			data = append(data, "-F", form)
+		}
+		req.Header.Del("Content-Type")
+	case !body.IsEmpty():
+		reader, contentType, err := body.Encode()
+		if err != nil {
+			return "", err
+		}
+		b, err := io.ReadAll(reader)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, "--data-raw", string(b))
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	switch {
+	case method == http.MethodHead:
+		args = append(args, "-I")
+	case method == http.MethodGet && len(data) == 0, method == http.MethodPost && len(data) > 0:
+	default:
+		args = append(args, "-X", method)
+	}
+	args = append(args, req.URL.String())
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, val := range req.Header[key] {
+			args = append(args, "-H", key+": "+val)
+		}
+	}
+	args = append(args, data...)
+
+	if r.Proxy != "" {
+		args = append(args, "-x", r.Proxy)
+	}
+	if r.Timeout > 0 {
+		args = append(args, "--max-time", strconv.FormatFloat(r.Timeout.Seconds(), 'f', -1, 64))
+	}
+	if t := r.TLS; t != nil {
+		if t.InsecureSkipVerify {
+			args = append(args, "-k")
+		}
+		for _, file := range t.CAFiles {
+			args = append(args, "--cacert", file)
+		}
+		if t.CertFile != "" {
+			args = append(args, "--cert", t.CertFile)
+		}
+		if t.KeyFile != "" {
+			args = append(args, "--key", t.KeyFile)
+		}
+	}
+
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " "), nil
+}
+
+// shellQuote 需要时用单引号包裹参数，兼容 bash、zsh 等 POSIX shell
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_./:@%+=,", c))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// curl 的短参数及对应的长参数
+var curlShort = map[string]string{
+	"-X": "--request", "-H": "--header", "-d": "--data", "-F": "--form", "-x": "--proxy", "-u": "--user",
+	"-A": "--user-agent", "-e": "--referer", "-b": "--cookie", "-m": "--max-time", "-G": "--get", "-I": "--head",
+	"-k": "--insecure", "-E": "--cert", "-L": "--location", "-s": "--silent", "-S": "--show-error",
+	"-v": "--verbose", "-i": "--include", "-g": "--globoff", "-N": "--no-buffer", "-o": "--output", "-w": "--write-out",
+}
+
+// 需要参数值的长参数
+var curlArgs = map[string]bool{
+	"--request": true, "--header": true, "--data": true, "--data-raw": true, "--data-ascii": true,
+	"--data-binary": true, "--data-urlencode": true, "--form": true, "--form-string": true, "--proxy": true,
+	"--user": true, "--user-agent": true, "--referer": true, "--cookie": true, "--max-time": true, "--url": true,
+	"--cacert": true, "--cert": true, "--key": true, "--output": true, "--write-out": true, "--connect-timeout": true,
+}
+
+// 不影响请求内容、直接忽略的参数
+var curlIgnored = map[string]bool{
+	"--location": true, "--silent": true, "--show-error": true, "--verbose": true, "--include": true,
+	"--globoff": true, "--no-buffer": true, "--output": true, "--write-out": true, "--connect-timeout": true,
+	"--http1.1": true, "--http2": true, "--http2-prior-knowledge": true, "--fail": true,
+}
+
+// ParseCurlOptions 解析 curl 命令的配置
+type ParseCurlOptions struct {
+	// AllowFiles 允许命令引用本地文件：-d @file、--data-urlencode name@file、-F name=@file、-F name=<file、
+	// --cacert、--cert、--key。默认不允许，引用文件时返回 ErrCurlFile，避免不可信的命令读取本地文件
+	AllowFiles bool
+}
+
+// ErrCurlFile curl 命令引用了本地文件，但没有设置 ParseCurlOptions.AllowFiles
+var ErrCurlFile = errors.New("curl 命令引用了本地文件，需要使用 ParseCurlWithOptions 并设置 AllowFiles")
+
+// checkFile 检查是否允许引用文件 path
+func (o *ParseCurlOptions) checkFile(path string) error {
+	if !o.AllowFiles {
+		return fmt.Errorf("%w: %s", ErrCurlFile, path)
+	}
+	return nil
+}
+
+// readFile 读取命令引用的文件
+func (o *ParseCurlOptions) readFile(path string) ([]byte, error) {
+	if err := o.checkFile(path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// ParseCurl 解析 curl 命令（例如浏览器开发者工具中“复制为 cURL (bash)”的结果），支持 -X、-H、-d、--data-raw、
+// --data-binary、--data-urlencode、-F、-x、-u、-b、-A、-e、-G、-I、-k、-m、--compressed 等常用参数，
+// 不支持的参数返回错误。--compressed 会移除 Accept-Encoding 请求头，由 Transport 自动协商 gzip 并解压。
+// 命令引用本地文件时返回 ErrCurlFile，解析可信的命令时使用 ParseCurlWithOptions
+func ParseCurl(cmd string) (*Request, error) {
+	return ParseCurlWithOptions(cmd, nil)
+}
+
+// ParseCurlWithOptions 按配置解析 curl 命令，opts 为空时与 ParseCurl 相同
+func ParseCurlWithOptions(cmd string, opts *ParseCurlOptions) (*Request, error) {
+	if opts == nil {
+		opts = &ParseCurlOptions{}
+	}
+
+	args, err := splitCurl(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, errors.New("不是 curl 命令")
+	}
+
+	r := &Request{Headers: S{}}
+	var (
+		url                           string
+		method                        string
+		data                          []string
+		form                          *Multipart
+		get, head, compressed, hasUrl bool
+	)
+	tlsOpts := func() *TLSOptions {
+		if r.TLS == nil {
+			r.TLS = &TLSOptions{}
+		}
+		return r.TLS
+	}
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			url, hasUrl = arg, true
+			continue
+		}
+		name, value, hasValue := arg, "", false
+		if !strings.HasPrefix(arg, "--") {
+			long, ok := curlShort[arg[:2]]
+			if !ok {
+				return nil, fmt.Errorf("不支持的 curl 参数 %s", arg)
+			}
+			name = long
+			if rest := arg[2:]; rest != "" {
+				if curlArgs[long] {
+					value, hasValue = rest, true
+				} else {
+					// 合并的短参数，例如 -sSL
+					expanded := []string{"-" + rest[:1]}
+					if len(rest) > 1 {
+						expanded = append(expanded, "-"+rest[1:])
+					}
+					args = slices.Insert(args, i+1, expanded...)
+				}
+			}
+		}
+		if curlArgs[name] && !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("curl 参数 %s 缺少值", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch name {
+		case "--request":
+			method = strings.ToUpper(value)
+		case "--url":
+			url, hasUrl = value, true
+		case "--header":
+			key, val, ok := strings.Cut(value, ":")
+			if val = strings.TrimSpace(val); ok && val != "" {
+				setHeader(r.Headers, strings.TrimSpace(key), val)
+			}
+		case "--user-agent":
+			setHeader(r.Headers, "User-Agent", value)
+		case "--referer":
+			setHeader(r.Headers, "Referer", value)
+		case "--cookie":
+			if !strings.Contains(value, "=") {
+				return nil, fmt.Errorf("不支持从文件 %s 读取 Cookie", value)
+			}
+			if old, ok := getHeader(r.Headers, "Cookie"); ok {
+				value = old + "; " + value
+			}
+			setHeader(r.Headers, "Cookie", value)
+		case "--data", "--data-ascii", "--data-binary", "--data-raw":
+			if name != "--data-raw" && strings.HasPrefix(value, "@") {
+				b, err := opts.readFile(value[1:])
+				if err != nil {
+					return nil, err
+				}
+				value = string(b)
+				if name != "--data-binary" {
+					value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+				}
+			}
+			data = append(data, value)
+		case "--data-urlencode":
+			value, err := curlUrlencode(value, opts)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, value)
+		case "--form", "--form-string":
+			if form == nil {
+				form = NewMultipart()
+			}
+			if err := curlForm(form, value, name == "--form-string", opts); err != nil {
+				return nil, err
+			}
+		case "--proxy":
+			r.Proxy = value
+		case "--user":
+			user, pass, _ := strings.Cut(value, ":")
+			r.Auth = &BasicAuth{Username: user, Password: pass}
+		case "--max-time":
+			secs, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("无效的超时 %s", value)
+			}
+			r.Timeout = time.Duration(secs * float64(time.Second))
+		case "--get":
+			get = true
+		case "--head":
+			head = true
+		case "--compressed":
+			compressed = true
+		case "--insecure":
+			tlsOpts().InsecureSkipVerify = true
+		case "--cacert", "--cert", "--key":
+			// 证书在发送时才读取，这里只检查是否允许引用文件
+			if err := opts.checkFile(value); err != nil {
+				return nil, err
+			}
+			switch name {
+			case "--cacert":
+				tlsOpts().CAFiles = append(tlsOpts().CAFiles, value)
+			case "--cert":
+				tlsOpts().CertFile = value
+			default:
+				tlsOpts().KeyFile = value
+			}
+		default:
+			if !curlIgnored[name] {
+				return nil, fmt.Errorf("不支持的 curl 参数 %s", name)
+			}
+		}
+	}
+
+	if !hasUrl {
+		return nil, errors.New("curl 命令缺少网址")
+	}
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+	if get && len(data) > 0 {
+		sep := "?"
+		if strings.Contains(url, "?") {
+			sep = "&"
+		}
+		url += sep + strings.Join(data, "&")
+		data = nil
+	}
+	r.Url = url
+
+	switch {
+	case method != "":
+		r.Method = method
+	case head:
+		r.Method = http.MethodHead
+	case len(data) > 0 || form != nil:
+		r.Method = http.MethodPost
+	default:
+		r.Method = http.MethodGet
+	}
+
+	contentType, _ := getHeader(r.Headers, "Content-Type")
+	switch {
+	case form != nil:
+		delHeader(r.Headers, "Content-Type")
+		r.Multipart = form
+	case len(data) > 0:
+		delHeader(r.Headers, "Content-Type")
+		r.Body = []byte(strings.Join(data, "&"))
+		r.ContentType = contentType
+		if r.ContentType == "" {
+			r.ContentType = "application/x-www-form-urlencoded"
+		}
+	}
+	if compressed {
+		delHeader(r.Headers, "Accept-Encoding")
+	}
+	return r, nil
+}
+
+// curlUrlencode 处理 --data-urlencode 的各种形式：content、=content、name=content、@file、name@file
+func curlUrlencode(value string, opts *ParseCurlOptions) (string, error) {
+	if i := strings.IndexAny(value, "=@"); i >= 0 {
+		name, content := value[:i], value[i+1:]
+		if value[i] == '@' {
+			b, err := opts.readFile(content)
+			if err != nil {
+				return "", err
+			}
+			content = string(b)
+		}
+		if name == "" {
+			return _url.QueryEscape(content), nil
+		}
+		return name + "=" + _url.QueryEscape(content), nil
+	}
+	return _url.QueryEscape(value), nil
+}
+
+// curlForm 处理 -F 的字段：name=value、name=@file;type=...;filename=...、name=<file。
+// @file 只记录路径，发送时才读取
+func curlForm(form *Multipart, value string, literal bool, opts *ParseCurlOptions) error {
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("无效的表单字段 %s", value)
+	}
+	switch {
+	case literal:
+		form.AddField(name, content)
+	case strings.HasPrefix(content, "@"):
+		parts := strings.Split(content[1:], ";")
+		if err := opts.checkFile(parts[0]); err != nil {
+			return err
+		}
+		f := File{Field: name, Path: parts[0]}
+		for _, param := range parts[1:] {
+			key, val, _ := strings.Cut(param, "=")
+			switch strings.TrimSpace(key) {
+			case "type":
+				f.ContentType = val
+			case "filename":
+				f.Filename = strings.Trim(val, `"`)
+			}
+		}
+		form.Add(f)
+	case strings.HasPrefix(content, "<"):
+		b, err := opts.readFile(content[1:])
+		if err != nil {
+			return err
+		}
+		form.AddField(name, string(b))
+	default:
+		form.AddField(name, content)
+	}
+	return nil
+}
+
+// getHeader、setHeader、delHeader 按不区分大小写的方式操作 S 中的请求头
+func getHeader(headers S, key string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func setHeader(headers S, key, val string) {
+	delHeader(headers, key)
+	headers[key] = val
+}
+
+func delHeader(headers S, key string) {
+	for k := range headers {
+		if strings.EqualFold(k, key) {
+			delete(headers, k)
+		}
+	}
+}
+
+// splitCurl 按 POSIX shell 的规则拆分命令，支持单引号、双引号、$'...'、反斜杠转义和续行
+func splitCurl(cmd string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inToken bool
+	)
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inToken {
+				args = append(args, cur.String())
+				cur.Reset()
+				inToken = false
+			}
+		case c == '\\':
+			if i+1 < len(cmd) {
+				i++
+				if cmd[i] == '\r' && i+1 < len(cmd) && cmd[i+1] == '\n' {
+					i++
+				}
+				if cmd[i] != '\n' {
+					cur.WriteByte(cmd[i])
+					inToken = true
+				}
+			}
+		case c == '\'':
+			end := strings.IndexByte(cmd[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("单引号没有闭合")
+			}
+			cur.WriteString(cmd[i+1 : i+1+end])
+			i += end + 1
+			inToken = true
+		case c == '$' && i+1 < len(cmd) && cmd[i+1] == '\'':
+			n, err := ansiCQuote(cmd[i+2:], &cur)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+			inToken = true
+		case c == '"':
+			i++
+			for ; i < len(cmd) && cmd[i] != '"'; i++ {
+				if cmd[i] == '\\' && i+1 < len(cmd) && strings.IndexByte("$`\"\\\n", cmd[i+1]) >= 0 {
+					i++
+					if cmd[i] == '\n' {
+						continue
+					}
+				}
+				cur.WriteByte(cmd[i])
+			}
+			if i >= len(cmd) {
+				return nil, errors.New("双引号没有闭合")
+			}
+			inToken = true
+		default:
+			cur.WriteByte(c)
+			inToken = true
+		}
+	}
+	if inToken {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// ansiCQuote 解析 $'...' 的内容（不含开头的 $'），返回消耗的字节数（包括结尾的单引号）
+func ansiCQuote(s string, out *strings.Builder) (int, error) {
+	escapes := map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', 'a': '\a', 'b': '\b', 'f': '\f', 'v': '\v', 'e': 0x1b, '\\': '\\', '\'': '\'', '"': '"', '?': '?'}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			return i + 1, nil
+		case c != '\\' || i+1 >= len(s):
+			out.WriteByte(c)
+		default:
+			i++
+			if e, ok := escapes[s[i]]; ok {
+				out.WriteByte(e)
+				continue
+			}
+			// \xHH、\uHHHH、\UHHHHHHHH
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[i]]
+			if size == 0 {
+				out.WriteByte('\\')
+				out.WriteByte(s[i])
+				continue
+			}
+			j := i + 1
+			for j < len(s) && j < i+1+size && isHex(s[j]) {
+				j++
+			}
+			n, err := strconv.ParseUint(s[i+1:j], 16, 32)
+			if err != nil {
+				return 0, fmt.Errorf("无效的转义 \\%s", s[i:j])
+			}
+			if s[i] == 'x' {
+				out.WriteByte(byte(n))
+			} else {
+				out.WriteString(string(rune(n)))
+			}
+			i = j - 1
+		}
+	}
+	return 0, errors.New("$' 没有闭合")
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
@@ -0,0 +1,196 @@
+package greqs
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequest_ToCurl(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{
+			"GET",
+			&Request{Method: "GET", Url: "https://example.com/a", Params: S{"q": "go lang"}, Headers: S{"X-Name": "Greqs"}},
+			`curl 'https://example.com/a?q=go+lang' -H 'X-Name: Greqs'`,
+		},
+		{
+			"JSON",
+			&Request{Method: "POST", Url: "https://example.com", Data: A{"msg": "it's"}, Timeout: 1500 * time.Millisecond, Proxy: "http://127.0.0.1:8080"},
+			`curl https://example.com -H 'Content-Type: application/json' --data-raw '{"msg":"it'\''s"}' -x http://127.0.0.1:8080 --max-time 1.5`,
+		},
+		{
+			"Form",
+			&Request{Method: "PUT", Url: "https://example.com", Form: S{"b": "2", "a": "1"}, Auth: &BasicAuth{Username: "user", Password: "pass"}},
+			`curl -u user:pass -X PUT https://example.com -H 'Content-Type: application/x-www-form-urlencoded' --data-raw 'a=1&b=2'`,
+		},
+		{
+			"Multipart",
+			&Request{Method: "POST", Url: "https://example.com", Headers: S{"Content-Type": "multipart/form-data"}, Multipart: NewMultipart().AddField("title", "a b").Add(File{Field: "file", Path: "/tmp/a.txt", ContentType: "text/plain"})},
+			`curl https://example.com --form-string 'title=a b' -F 'file=@/tmp/a.txt;type=text/plain'`,
+		},
+		{
+			"HEAD",
+			&Request{Method: "HEAD", Url: "https://example.com", Auth: &BearerAuth{Token: "t"}, TLS: &TLSOptions{InsecureSkipVerify: true, CAFiles: []string{"ca.pem"}}},
+			`curl -I https://example.com -H 'Authorization: Bearer t' -k --cacert ca.pem`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.req.ToCurl()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("\ngot  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (&Request{Method: "POST", Url: "https://example.com", Reader: strings.NewReader("x")}).ToCurl(); err == nil {
+		t.Error("流式请求体应该返回错误")
+	}
+}
+
+func TestParseCurl(t *testing.T) {
+	// 浏览器“复制为 cURL (bash)”的格式
+	cmd := `curl 'https://example.com/api?x=1' \
+  -H 'accept: application/json' \
+  -H 'accept-encoding: gzip, deflate, br, zstd' \
+  -H 'content-type: application/json' \
+  -b 'sid=abc; theme=dark' \
+  -H $'x-note: it\'sé' \
+  --data-raw $'{"name":"tom\\n"}' \
+  --compressed`
+	r, err := ParseCurl(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Request{
+		Method:      "POST",
+		Url:         "https://example.com/api?x=1",
+		Headers:     S{"accept": "application/json", "Cookie": "sid=abc; theme=dark", "x-note": "it'sé"},
+		Body:        []byte(`{"name":"tom\n"}`),
+		ContentType: "application/json",
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Fatalf("\ngot  %+v\nwant %+v", r, want)
+	}
+
+	tests := []struct {
+		cmd   string
+		check func(r *Request) bool
+	}{
+		{`curl -XPUT example.com -d a=1 -d b=2`, func(r *Request) bool {
+			return r.Method == "PUT" && r.Url == "http://example.com" && string(r.Body) == "a=1&b=2" && r.ContentType == "application/x-www-form-urlencoded"
+		}},
+		{`curl -G "https://example.com/s?a=1" --data-urlencode "q=go lang" --data-urlencode "=x&y"`, func(r *Request) bool {
+			return r.Method == "GET" && r.Url == "https://example.com/s?a=1&q=go+lang&x%26y" && r.Body == nil
+		}},
+		{`curl -sSL -u user:pass -x socks5://127.0.0.1:1080 -m 2.5 -k https://example.com`, func(r *Request) bool {
+			a, ok := r.Auth.(*BasicAuth)
+			return ok && a.Username == "user" && a.Password == "pass" && r.Proxy == "socks5://127.0.0.1:1080" &&
+				r.Timeout == 2500*time.Millisecond && r.TLS.InsecureSkipVerify && r.Method == "GET"
+		}},
+		{`curl -I --url https://example.com -A Greqs -e https://ref.com`, func(r *Request) bool {
+			return r.Method == "HEAD" && r.Url == "https://example.com" && r.Headers["User-Agent"] == "Greqs" && r.Headers["Referer"] == "https://ref.com"
+		}},
+		{`curl https://example.com -F "name=tom" -F "file=@/tmp/a.txt;type=text/plain;filename=b.txt" -H "Content-Type: multipart/form-data"`, func(r *Request) bool {
+			m := r.Multipart
+			return r.Method == "POST" && len(r.Headers) == 0 && m != nil && reflect.DeepEqual(m.fields, [][2]string{{"name", "tom"}}) &&
+				reflect.DeepEqual(m.files, []File{{Field: "file", Path: "/tmp/a.txt", Filename: "b.txt", ContentType: "text/plain"}})
+		}},
+	}
+	for _, tt := range tests {
+		r, err := ParseCurlWithOptions(tt.cmd, &ParseCurlOptions{AllowFiles: true})
+		if err != nil {
+			t.Errorf("%s: %v", tt.cmd, err)
+			continue
+		}
+		if !tt.check(r) {
+			t.Errorf("%s: %+v", tt.cmd, r)
+		}
+	}
+
+	for _, cmd := range []string{`wget https://example.com`, `curl`, `curl --unknown https://example.com`, `curl 'https://example.com`, `curl -H`, `curl -b cookies.txt https://example.com`} {
+		if _, err := ParseCurl(cmd); err == nil {
+			t.Errorf("%s 应该返回错误", cmd)
+		}
+	}
+
+	// 默认不允许引用本地文件
+	for _, cmd := range []string{`curl -d @/etc/passwd example.com`, `curl --data-urlencode q@/etc/passwd example.com`,
+		`curl -F f=@/etc/passwd example.com`, `curl -F "f=</etc/passwd" example.com`, `curl --cacert ca.pem https://example.com`} {
+		if _, err := ParseCurl(cmd); !errors.Is(err, ErrCurlFile) {
+			t.Errorf("%s: err = %v", cmd, err)
+		}
+	}
+}
+
+type curlItem struct {
+	Name string `xml:"name"`
+}
+
+func TestCurl_RoundTrip(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+	file := t.TempDir() + "/a.txt"
+	os.WriteFile(file, []byte("hello"), 0o644)
+
+	reqs := []*Request{
+		{Method: "PATCH", Url: srv.URL, Params: S{"page": "1"}, Data: A{"name": "Greqs"}},
+		{Method: "POST", Url: srv.URL, Form: S{"a": "1 2", "b": "&"}},
+		{Method: "POST", Url: srv.URL, XML: &curlItem{Name: "Greqs"}},
+		{Method: "DELETE", Url: srv.URL, Headers: S{"X-Id": "1"}, Auth: &APIKey{Name: "key", Value: "k", Query: true}},
+		{Method: "POST", Url: srv.URL, Multipart: NewMultipart().AddField("title", "hi").AddFile("file", file)},
+	}
+	for _, req := range reqs {
+		cmd, err := req.ToCurl()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseCurlWithOptions(cmd, &ParseCurlOptions{AllowFiles: true})
+		if err != nil {
+			t.Fatalf("%s: %v", cmd, err)
+		}
+		want, err := req.Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parsed.Do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range []string{"X-Method", "X-Query", "X-Content-Type"} {
+			if h == "X-Content-Type" && req.Multipart != nil {
+				continue // 分隔符不同
+			}
+			if got.Header.Get(h) != want.Header.Get(h) {
+				t.Errorf("%s: %s = %q, want %q", cmd, h, got.Header.Get(h), want.Header.Get(h))
+			}
+		}
+		if req.Multipart == nil && got.Text() != want.Text() {
+			t.Errorf("%s: body = %q, want %q", cmd, got.Text(), want.Text())
+		}
+		if req.Multipart != nil && (!strings.Contains(got.Text(), "hello") || !strings.Contains(got.Text(), `name="title"`)) {
+			t.Errorf("%s: body = %q", cmd, got.Text())
+		}
+	}
+}
+
+func TestSplitCurl(t *testing.T) {
+	got, err := splitCurl("curl  a\\ b 'c d'\"e \\\"f\\\"\" $'g\\x41\\t' \\\n h ''")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"curl", "a b", `c de "f"`, "gA\t", "h", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+package greqs
+
+import "context"
+
+// As 把响应解码为 T 类型
+func As[T any](resp *Response, opts DecodeOptions) (T, error) {
+	var v T
+	err := resp.DecodeWith(&v, opts)
+	return v, err
+}
+
+// decodeResult 请求成功时把响应解码为 T 类型
+func decodeResult[T any](resp *Response, err error) (T, *Response, error) {
+	var v T
+	if err != nil {
+		return v, resp, err
+	}
+	v, err = As[T](resp, DecodeOptions{})
+	return v, resp, err
+}
+
+// GetJSON 发送 GET 请求，并把响应的 JSON 数据解码为 T 类型
+func GetJSON[T any](url string, headers S) (T, *Response, error) {
+	return decodeResult[T](Get(url, headers))
+}
+
+// GetJSONCtx 发送 GET 请求，并把响应的 JSON 数据解码为 T 类型（携带上下文）
+func GetJSONCtx[T any](ctx context.Context, url string, headers S) (T, *Response, error) {
+	return decodeResult[T](GetCtx(ctx, url, headers))
+}
+
+// PostJSON 发送 POST 请求（JSON 形式），并把响应的 JSON 数据解码为 T 类型
+func PostJSON[T any](url string, headers S, data A) (T, *Response, error) {
+	return decodeResult[T](Post(url, headers, data))
+}
+
+// SendJSON 发送请求，并把响应的 JSON 数据解码为 T 类型
+func SendJSON[T any](method, url string, opts *Options) (T, *Response, error) {
+	return decodeResult[T](Send(method, url, opts))
+}
+
+// WorkerGetJSON 使用 Worker 发送 GET 请求，并把响应的 JSON 数据解码为 T 类型（Go 的方法不支持泛型）
+func WorkerGetJSON[T any](w *Worker, url string, headers S) (T, *Response, error) {
+	return decodeResult[T](w.Get(url, headers))
+}
+
+// WorkerPostJSON 使用 Worker 发送 POST 请求（JSON 形式），并把响应的 JSON 数据解码为 T 类型
+func WorkerPostJSON[T any](w *Worker, url string, headers S, data A) (T, *Response, error) {
+	return decodeResult[T](w.Post(url, headers, data))
+}
+
+// WorkerSendJSON 使用 Worker 发送任意方法的请求，并把响应的 JSON 数据解码为 T 类型
+func WorkerSendJSON[T any](ctx context.Context, w *Worker, method, url string, headers S, data A, form S) (T, *Response, error) {
+	return decodeResult[T](w.SendContext(ctx, method, url, headers, data, form))
+}
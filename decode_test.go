@@ -0,0 +1,72 @@
+package greqs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newJSONServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+}
+
+func TestGetJSON(t *testing.T) {
+	srv := newJSONServer(`[{"id":1,"name":"Greqs"},{"id":2,"name":"Go"}]`)
+	defer srv.Close()
+
+	users, resp, err := GetJSON[[]user](srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || len(users) != 2 || users[1].Name != "Go" {
+		t.Errorf("users = %+v", users)
+	}
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	first, _, err := WorkerGetJSON[[]user](worker, srv.URL, nil)
+	if err != nil || first[0].ID != 1 {
+		t.Errorf("WorkerGetJSON = %+v, %v", first, err)
+	}
+
+	v, err := resp.JSONValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arr, ok := v.([]any); !ok || len(arr) != 2 {
+		t.Errorf("JSONValue = %v", v)
+	}
+}
+
+func TestResponse_DecodeWith(t *testing.T) {
+	resp := &Response{Body: []byte(`{"id":12345678901234567,"name":"Greqs","extra":true}`)}
+
+	var u user
+	if err := resp.Decode(&u); err != nil || u.Name != "Greqs" {
+		t.Errorf("Decode = %+v, %v", u, err)
+	}
+	if err := resp.DecodeWith(&u, DecodeOptions{Strict: true}); err == nil {
+		t.Error("strict mode should reject unknown field")
+	}
+
+	m, err := As[map[string]any](resp, DecodeOptions{UseNumber: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := m["id"].(json.Number); !ok || n.String() != "12345678901234567" {
+		t.Errorf("id = %#v", m["id"])
+	}
+
+	if n, err := As[int](&Response{Body: []byte(`42`)}, DecodeOptions{}); err != nil || n != 42 {
+		t.Errorf("scalar = %d, %v", n, err)
+	}
+}
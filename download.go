@@ -0,0 +1,315 @@
+package greqs
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DownloadOptions 下载配置
+type DownloadOptions struct {
+	Headers  S                       // 请求头
+	Size     int64                   // 期望的文件大小，0 表示不校验（仍会与服务端声明的大小比较）
+	SHA256   string                  // 期望的 SHA-256（十六进制），为空表示不校验
+	MD5      string                  // 期望的 MD5（十六进制），为空表示不校验
+	Progress func(done, total int64) // 进度回调，total 未知时为 -1
+}
+
+// DownloadResult 下载结果
+type DownloadResult struct {
+	Path    string // 文件路径
+	Size    int64  // 文件大小
+	Resumed bool   // 是否从已下载的部分继续
+}
+
+// partMeta 未完成下载的元信息，用于 If-Range
+type partMeta struct {
+	Url          string `json:"url"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+}
+
+// validator If-Range 使用的校验值，优先使用强 ETag
+func (m *partMeta) validator() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// Download 下载文件到 path。先写入 path + ".part"，完成并校验后原子重命名；
+// 已存在的 .part 文件会通过 Range / If-Range 继续下载，连接中断时按 Worker 的重试策略继续。
+// Worker 的超时作为空闲超时，只要持续收到数据，下载就不会因为总耗时过长而中断。
+func (w *Worker) Download(ctx context.Context, url, path string, opts *DownloadOptions) (*DownloadResult, error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	partPath, metaPath := path+".part", path+".part.json"
+
+	result := &DownloadResult{Path: path}
+	err := w.resume(ctx, 1, func() error {
+		resumed, err := w.downloadPart(ctx, url, partPath, metaPath, opts)
+		result.Resumed = result.Resumed || resumed
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := verifyFile(partPath, opts)
+	if err != nil {
+		os.Remove(partPath)
+		os.Remove(metaPath)
+		return nil, err
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return nil, err
+	}
+	os.Remove(metaPath)
+	result.Size = size
+	return result, nil
+}
+
+// errInterrupted 下载中断，可以继续。resp 为触发重试的响应，用于读取 Retry-After
+type errInterrupted struct {
+	err  error
+	resp *Response
+}
+
+func (e *errInterrupted) Error() string { return fmt.Sprintf("下载中断: %v", e.err) }
+func (e *errInterrupted) Unwrap() error { return e.err }
+
+func isResumable(err error) bool {
+	var e *errInterrupted
+	return errors.As(err, &e)
+}
+
+// resume 执行 fn，返回可以继续的错误时按 Worker 的重试策略退避后再次执行，
+// 最多尝试 MaxAttempts 次（没有重试策略时为 attempts 次）。
+// fn 中的请求本身不重试，重试只发生在这一层
+func (w *Worker) resume(ctx context.Context, attempts int, fn func() error) error {
+	policy := w.GetRetry()
+	if policy != nil && policy.MaxAttempts > 1 {
+		attempts = policy.MaxAttempts
+	}
+	if policy == nil {
+		policy = &RetryPolicy{}
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || ctx.Err() != nil || attempt >= attempts || !isResumable(err) {
+			return err
+		}
+
+		var resp *Response
+		var e *errInterrupted
+		if errors.As(err, &e) {
+			resp = e.resp
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, resp, err)
+		}
+		timer := time.NewTimer(policy.Backoff(attempt, resp))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// goDownload 发送下载使用的流式请求：不重试，Worker 的超时作为空闲超时
+func (w *Worker) goDownload(ctx context.Context, req *http.Request) (*Response, error) {
+	return w.do(ctx, req, fetchDownload, nil)
+}
+
+// downloadPart 下载（或继续下载）到 partPath，返回是否为继续下载
+func (w *Worker) downloadPart(ctx context.Context, url, partPath, metaPath string, opts *DownloadOptions) (bool, error) {
+	var offset int64
+	var meta partMeta
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+	if b, err := os.ReadFile(metaPath); err == nil {
+		json.Unmarshal(b, &meta)
+	}
+	if meta.Url != url || meta.validator() == "" {
+		offset = 0
+	}
+
+	req, err := MakeGetRequest(url, opts.Headers)
+	if err != nil {
+		return false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", meta.validator())
+	}
+
+	resp, err := w.goDownload(ctx, req)
+	if err != nil {
+		return false, &errInterrupted{err: err}
+	}
+	defer resp.Close()
+
+	total := int64(-1)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		offset = 0
+		if resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+	case http.StatusPartialContent:
+		start, size, ok := ParseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return false, fmt.Errorf("无效的 Content-Range: %s", resp.Header.Get("Content-Range"))
+		}
+		total = size
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 已下载完整
+		if _, size, ok := ParseContentRange(resp.Header.Get("Content-Range")); ok && size == offset {
+			return true, nil
+		}
+		// 已下载的部分无效，删除后在本次调用中从头下载
+		os.Remove(partPath)
+		os.Remove(metaPath)
+		if offset == 0 {
+			return false, fmt.Errorf("下载失败，状态码 %d", resp.StatusCode)
+		}
+		resp.Close()
+		return w.downloadPart(ctx, url, partPath, metaPath, opts)
+	default:
+		err := fmt.Errorf("下载失败，状态码 %d", resp.StatusCode)
+		if policy := w.GetRetry(); policy != nil && policy.ShouldRetry(resp, nil) {
+			return false, &errInterrupted{err: err, resp: resp}
+		}
+		return false, err
+	}
+
+	if offset == 0 {
+		meta = partMeta{Url: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+		b, _ := json.Marshal(meta)
+		if err := os.WriteFile(metaPath, b, 0644); err != nil {
+			return false, err
+		}
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(partPath, flag, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	pw := &progressWriter{w: file, done: offset, total: total, fn: opts.Progress}
+	if _, err := io.Copy(pw, resp.Reader()); err != nil {
+		return offset > 0, &errInterrupted{err: err}
+	}
+	if total >= 0 && pw.done != total {
+		return offset > 0, &errInterrupted{err: fmt.Errorf("已下载 %d 字节，期望 %d 字节", pw.done, total)}
+	}
+	if opts.Size > 0 && total >= 0 && total != opts.Size {
+		// 文件与期望的不一致，不保留 .part，避免之后在错误的数据上继续下载
+		file.Close()
+		os.Remove(partPath)
+		os.Remove(metaPath)
+		return offset > 0, fmt.Errorf("文件大小为 %d，期望 %d", total, opts.Size)
+	}
+	return offset > 0, nil
+}
+
+// verifyFile 校验文件大小和摘要，返回文件大小
+func verifyFile(path string, opts *DownloadOptions) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writers := []io.Writer{io.Discard}
+	sha, md := sha256.New(), md5.New()
+	if opts.SHA256 != "" {
+		writers = append(writers, sha)
+	}
+	if opts.MD5 != "" {
+		writers = append(writers, md)
+	}
+	size, err := io.Copy(io.MultiWriter(writers...), file)
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.Size > 0 && size != opts.Size {
+		return 0, fmt.Errorf("文件大小为 %d，期望 %d", size, opts.Size)
+	}
+	if opts.SHA256 != "" && !strings.EqualFold(hex.EncodeToString(sha.Sum(nil)), opts.SHA256) {
+		return 0, fmt.Errorf("SHA-256 校验失败")
+	}
+	if opts.MD5 != "" && !strings.EqualFold(hex.EncodeToString(md.Sum(nil)), opts.MD5) {
+		return 0, fmt.Errorf("MD5 校验失败")
+	}
+	return size, nil
+}
+
+// ParseContentRange 解析 Content-Range 响应头，返回起始位置和文件总大小（未知时为 -1）
+func ParseContentRange(value string) (start, total int64, ok bool) {
+	value, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, size, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = n
+	}
+	if rng == "*" {
+		return 0, total, true
+	}
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// progressWriter 写入时回调进度
+type progressWriter struct {
+	w     io.Writer
+	done  int64
+	total int64
+	fn    func(done, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if p.fn != nil {
+		p.fn(p.done, p.total)
+	}
+	return n, err
+}
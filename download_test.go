@@ -0,0 +1,168 @@
+package greqs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 本地文件服务，支持 Range；前 drops 次请求只写一半就断开连接
+func newFileServer(content []byte, drops int32) (*httptest.Server, *int32) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if atomic.AddInt32(&count, 1) <= drops {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	return srv, &count
+}
+
+func TestWorker_DownloadResume(t *testing.T) {
+	content := bytes.Repeat([]byte("greqs"), 20000)
+	sum := sha256.Sum256(content)
+	srv, count := newFileServer(content, 1)
+	defer srv.Close()
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetRetry(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	var last int64
+	path := filepath.Join(t.TempDir(), "file.bin")
+	result, err := worker.Download(context.Background(), srv.URL, path, &DownloadOptions{
+		Size:     int64(len(content)),
+		SHA256:   hex.EncodeToString(sum[:]),
+		Progress: func(done, total int64) { last = done },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Resumed || result.Size != int64(len(content)) || *count != 2 || last != int64(len(content)) {
+		t.Errorf("result = %+v, count = %d, progress = %d", result, *count, last)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, content) {
+		t.Error("content mismatch")
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Error(".part file should be renamed")
+	}
+}
+
+func TestWorker_DownloadChecksum(t *testing.T) {
+	srv, _ := newFileServer([]byte("hello"), 0)
+	defer srv.Close()
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if _, err := worker.Download(context.Background(), srv.URL, path, &DownloadOptions{MD5: "00"}); err == nil {
+		t.Error("expected checksum error")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("file should not exist after checksum error")
+	}
+	if _, err := worker.Download(context.Background(), srv.URL, path, &DownloadOptions{MD5: "5d41402abc4b2a76b9719d911017c592"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorker_DownloadInvalidPart(t *testing.T) {
+	content := []byte("hello")
+	srv, count := newFileServer(content, 0)
+	defer srv.Close()
+
+	// .part 比服务端的文件大，服务端返回 416，本次调用中从头下载
+	path := filepath.Join(t.TempDir(), "file.bin")
+	os.WriteFile(path+".part", []byte("stale data"), 0644)
+	os.WriteFile(path+".part.json", []byte(`{"url":"`+srv.URL+`","etag":"\"v1\""}`), 0644)
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	if _, err := worker.Download(context.Background(), srv.URL, path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, content) || *count != 2 {
+		t.Errorf("content = %q, count = %d", got, *count)
+	}
+
+	// 大小与期望不一致时不保留 .part
+	if _, err := worker.Download(context.Background(), srv.URL, path+"2", &DownloadOptions{Size: 10}); err == nil {
+		t.Fatal("expected size error")
+	}
+	for _, p := range []string{path + "2.part", path + "2.part.json"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", p)
+		}
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	cases := map[string][3]int64{
+		"bytes 0-99/1000": {0, 1000, 1},
+		"bytes 100-199/*": {100, -1, 1},
+		"bytes */1000":    {0, 1000, 1},
+		"bits 0-1/2":      {0, 0, 0},
+		"bytes 0-99/abc":  {0, 0, 0},
+	}
+	for value, want := range cases {
+		start, total, ok := ParseContentRange(value)
+		if start != want[0] || total != want[1] || ok != (want[2] == 1) {
+			t.Errorf("%s: got %d %d %v", value, start, total, ok)
+		}
+	}
+}
+
+func TestWorker_DownloadTimeout(t *testing.T) {
+	// 每 40ms 发送一块数据，总耗时超过 Worker 的超时，但从不空闲超过超时
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5")
+		for i := 0; i < 5; i++ {
+			time.Sleep(40 * time.Millisecond)
+			w.Write([]byte("x"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	worker := NewWorker("", 100*time.Millisecond, nil, nil)
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if _, err := worker.Download(context.Background(), srv.URL, path, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 空闲超过超时则中断
+	worker.SetTimeout(30 * time.Millisecond)
+	if _, err := worker.Download(context.Background(), srv.URL, path, nil); err == nil {
+		t.Error("空闲超时应该中断下载")
+	}
+}
+
+func TestWorker_DownloadRetry(t *testing.T) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// 只在下载这一层重试，不会与 Worker 的重试叠加
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetRetry(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	if _, err := worker.Download(context.Background(), srv.URL, filepath.Join(t.TempDir(), "file.bin"), nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if count != 3 {
+		t.Errorf("请求了 %d 次，期望 3 次", count)
+	}
+}
@@ -0,0 +1,347 @@
+// Package greqstest 提供用于单元测试的模拟 Transport，无需启动服务即可测试使用 greqs 的代码。
+//
+//	mock := greqstest.NewMock()
+//	mock.Expect("GET", "https://api.example.com/users/*").ReplyJSON(200, greqs.A{"id": 1})
+//	mock.Expect("POST", "https://api.example.com/users").MatchJSON(greqs.A{"name": "tom"}).Reply(201, "")
+//
+//	worker.SetTransport(mock)                               // Worker 使用 SetTransport 注入
+//	greqs.Send("GET", url, &greqs.Options{Transport: mock}) // 单个请求使用 Options.Transport 或 Request.Transport
+//	...
+//	mock.AssertExpectations(t)
+//
+// Install 替换整个进程的默认 Transport，只适合无法注入 Transport 的代码，不能用于并行测试
+package greqstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"greqs"
+)
+
+// Matcher 自定义请求匹配，body 为完整的请求体
+type Matcher func(req *http.Request, body []byte) bool
+
+// UnexpectedRequestError 没有匹配的期望（或匹配的期望已用完次数）
+type UnexpectedRequestError struct {
+	Method string
+	Url    string
+}
+
+func (e *UnexpectedRequestError) Error() string {
+	return fmt.Sprintf("没有匹配的期望: %s %s", e.Method, e.Url)
+}
+
+// Mock 模拟 Transport，按注册顺序查找第一个匹配且未用完次数的期望
+type Mock struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	unexpected   []string // 没有匹配的请求
+}
+
+// NewMock 创建模拟 Transport
+func NewMock() *Mock {
+	return &Mock{}
+}
+
+// Install 让包级函数使用该模拟 Transport，测试结束时恢复。
+// 替换的是整个进程的默认 Transport，同一进程中其他并行的测试（t.Parallel）也会使用它，
+// 不能与并行测试一起使用；能注入时优先使用 Worker.SetTransport、Options.Transport 或 Request.Transport
+func (m *Mock) Install(t testing.TB) {
+	old := greqs.SetDefaultTransport(m)
+	t.Cleanup(func() { greqs.SetDefaultTransport(old) })
+}
+
+// Expect 注册期望，method 为空时匹配任意方法。pattern 中的 * 匹配任意字符，
+// pattern 不含 ? 时忽略请求的查询参数，例如 https://api.example.com/users/*
+func (m *Mock) Expect(method, pattern string) *Expectation {
+	return m.add(method, func(u string) bool { return matchPattern(pattern, u) }, pattern)
+}
+
+// ExpectRegexp 注册期望，网址（包括查询参数）需要匹配正则表达式
+func (m *Mock) ExpectRegexp(method string, re *regexp.Regexp) *Expectation {
+	return m.add(method, re.MatchString, re.String())
+}
+
+func (m *Mock) add(method string, url func(string) bool, desc string) *Expectation {
+	e := &Expectation{mock: m, method: strings.ToUpper(method), url: url, desc: desc, times: 1, status: http.StatusOK, header: http.Header{}}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	var e *Expectation
+	for _, candidate := range m.expectations {
+		if candidate.exhausted() || !candidate.match(req, body) {
+			continue
+		}
+		candidate.calls++
+		e = candidate
+		break
+	}
+	if e == nil {
+		m.unexpected = append(m.unexpected, req.Method+" "+req.URL.String())
+	}
+	m.mu.Unlock()
+
+	if e == nil {
+		return nil, &UnexpectedRequestError{Method: req.Method, Url: req.URL.String()}
+	}
+	if e.delay > 0 {
+		timer := time.NewTimer(e.delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	return e.respond(req, body)
+}
+
+// AssertExpectations 检查所有期望都达到了调用次数，并且没有意外的请求
+func (m *Mock) AssertExpectations(t testing.TB) {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if e.times >= 0 && e.calls < e.times {
+			t.Errorf("期望 %s 被调用 %d 次，实际 %d 次", e, e.times, e.calls)
+		}
+	}
+	for _, req := range m.unexpected {
+		t.Errorf("意外的请求: %s", req)
+	}
+}
+
+// Reset 清空期望和记录
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations, m.unexpected = nil, nil
+}
+
+// Expectation 期望，匹配方法和网址，可以追加请求头、查询参数和请求体条件
+type Expectation struct {
+	mock     *Mock
+	method   string
+	url      func(string) bool
+	desc     string
+	matchers []Matcher
+	times    int // 期望的调用次数，-1 表示不限
+	calls    int
+
+	status  int
+	header  http.Header
+	body    []byte
+	err     error
+	handler func(req *http.Request, body []byte) (*http.Response, error)
+	delay   time.Duration
+}
+
+func (e *Expectation) String() string {
+	method := e.method
+	if method == "" {
+		method = "*"
+	}
+	return method + " " + e.desc
+}
+
+// MatchHeader 请求头需要等于 value
+func (e *Expectation) MatchHeader(key, value string) *Expectation {
+	return e.MatchFunc(func(req *http.Request, _ []byte) bool { return req.Header.Get(key) == value })
+}
+
+// MatchQuery 查询参数需要等于 value
+func (e *Expectation) MatchQuery(key, value string) *Expectation {
+	return e.MatchFunc(func(req *http.Request, _ []byte) bool {
+		q := req.URL.Query()
+		return q.Has(key) && q.Get(key) == value
+	})
+}
+
+// MatchBody 请求体需要等于 body
+func (e *Expectation) MatchBody(body string) *Expectation {
+	return e.MatchFunc(func(_ *http.Request, b []byte) bool { return string(b) == body })
+}
+
+// MatchBodyContains 请求体需要包含 s
+func (e *Expectation) MatchBodyContains(s string) *Expectation {
+	return e.MatchFunc(func(_ *http.Request, b []byte) bool { return bytes.Contains(b, []byte(s)) })
+}
+
+// MatchJSON 请求体解析后需要与 v 序列化后的 JSON 相等（忽略键的顺序和空白）
+func (e *Expectation) MatchJSON(v any) *Expectation {
+	want, err := normalizeJSON(v)
+	return e.MatchFunc(func(_ *http.Request, b []byte) bool {
+		var got any
+		if err != nil || json.Unmarshal(b, &got) != nil {
+			return false
+		}
+		return reflect.DeepEqual(got, want)
+	})
+}
+
+// MatchFunc 添加自定义匹配条件
+func (e *Expectation) MatchFunc(fn Matcher) *Expectation {
+	e.matchers = append(e.matchers, fn)
+	return e
+}
+
+// Times 设置期望的调用次数，默认 1 次
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// AnyTimes 不限调用次数（包括 0 次）
+func (e *Expectation) AnyTimes() *Expectation {
+	e.times = -1
+	return e
+}
+
+// Delay 模拟延迟，请求的上下文取消时提前返回
+func (e *Expectation) Delay(d time.Duration) *Expectation {
+	e.delay = d
+	return e
+}
+
+// Reply 返回指定状态码和响应体
+func (e *Expectation) Reply(status int, body string) *Expectation {
+	e.status, e.body = status, []byte(body)
+	return e
+}
+
+// ReplyJSON 返回 JSON 响应体，并设置 Content-Type
+func (e *Expectation) ReplyJSON(status int, v any) *Expectation {
+	b, err := json.Marshal(v)
+	if err != nil {
+		e.err = err
+		return e
+	}
+	e.header.Set("Content-Type", "application/json")
+	e.status, e.body = status, b
+	return e
+}
+
+// ReplyHeader 设置响应头
+func (e *Expectation) ReplyHeader(key, value string) *Expectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// ReplyError 返回错误（例如模拟网络错误）
+func (e *Expectation) ReplyError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// ReplyFunc 由 fn 生成响应，body 为完整的请求体
+func (e *Expectation) ReplyFunc(fn func(req *http.Request, body []byte) (*http.Response, error)) *Expectation {
+	e.handler = fn
+	return e
+}
+
+// Calls 已匹配的次数
+func (e *Expectation) Calls() int {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	return e.calls
+}
+
+func (e *Expectation) exhausted() bool {
+	return e.times >= 0 && e.calls >= e.times
+}
+
+func (e *Expectation) match(req *http.Request, body []byte) bool {
+	if e.method != "" && e.method != req.Method {
+		return false
+	}
+	if !e.url(req.URL.String()) {
+		return false
+	}
+	for _, m := range e.matchers {
+		if !m(req, body) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) respond(req *http.Request, body []byte) (*http.Response, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.handler != nil {
+		return e.handler(req, body)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}, nil
+}
+
+// matchPattern 匹配网址，* 匹配任意字符，pattern 不含 ? 时忽略查询参数
+func matchPattern(pattern, u string) bool {
+	if !strings.Contains(pattern, "?") {
+		u, _, _ = strings.Cut(u, "?")
+	}
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return u == pattern
+	}
+	if !strings.HasPrefix(u, parts[0]) {
+		return false
+	}
+	u = u[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(u, part)
+		if i < 0 {
+			return false
+		}
+		u = u[i+len(part):]
+	}
+	return strings.HasSuffix(u, parts[len(parts)-1])
+}
+
+// normalizeJSON 把 v 序列化后再解析，得到可以与请求体比较的值
+func normalizeJSON(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
@@ -0,0 +1,171 @@
+package greqstest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"greqs"
+)
+
+// 记录错误而不是让测试失败，用于检查 AssertExpectations 的输出
+type recorder struct {
+	testing.TB
+	mu     sync.Mutex
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, format)
+}
+
+func TestMock_PackageLevel(t *testing.T) {
+	mock := NewMock()
+	mock.Install(t)
+	mock.Expect("GET", "https://api.example.com/users/*").MatchQuery("page", "2").ReplyJSON(200, greqs.A{"id": 1})
+	mock.Expect("POST", "https://api.example.com/users").MatchJSON(greqs.A{"name": "tom", "age": 18}).
+		ReplyHeader("Location", "/users/2").Reply(201, "created")
+	mock.Expect("", "https://api.example.com/form").MatchBody("a=1&b=2").MatchHeader("Content-Type", "application/x-www-form-urlencoded").Reply(204, "")
+
+	resp, err := greqs.Get("https://api.example.com/users/1?page=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := resp.JSON(); resp.StatusCode != 200 || data["id"] != float64(1) || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("%d %v %v", resp.StatusCode, data, resp.Header)
+	}
+
+	// JSON 比较忽略键的顺序
+	resp, err = greqs.Send("POST", "https://api.example.com/users", &greqs.Options{Body: []byte(`{"age":18, "name":"tom"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 201 || resp.Text() != "created" || resp.Header.Get("Location") != "/users/2" {
+		t.Fatalf("%d %q %v", resp.StatusCode, resp.Text(), resp.Header)
+	}
+
+	if resp, err := greqs.PostForm("https://api.example.com/form", nil, greqs.S{"a": "1", "b": "2"}); err != nil || resp.StatusCode != 204 {
+		t.Fatal(resp, err)
+	}
+	mock.AssertExpectations(t)
+
+	// 期望已用完，再次请求返回 *UnexpectedRequestError
+	_, err = greqs.Get("https://api.example.com/users/1?page=2", nil)
+	var ue *UnexpectedRequestError
+	if !errors.As(err, &ue) || ue.Method != "GET" {
+		t.Fatalf("err = %v", err)
+	}
+	r := &recorder{TB: t}
+	mock.AssertExpectations(r)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "意外的请求") {
+		t.Errorf("errors = %v", r.errors)
+	}
+}
+
+func TestMock_Request(t *testing.T) {
+	t.Parallel()
+	mock := NewMock()
+	mock.Expect("GET", "https://api.example.com/a").Reply(200, "a")
+	mock.Expect("GET", "https://api.example.com/b").Reply(200, "b")
+
+	// 按请求注入，不影响包级函数和其他并行的测试
+	resp, err := greqs.Send("GET", "https://api.example.com/a", &greqs.Options{Transport: mock})
+	if err != nil || resp.Text() != "a" {
+		t.Fatal(resp, err)
+	}
+	resp, err = (&greqs.Request{Method: "GET", Url: "https://api.example.com/b", Transport: mock}).Do()
+	if err != nil || resp.Text() != "b" {
+		t.Fatal(resp, err)
+	}
+	mock.AssertExpectations(t)
+}
+
+func TestMock_Worker(t *testing.T) {
+	mock := NewMock()
+	mock.ExpectRegexp("GET", regexp.MustCompile(`/items/\d+$`)).Times(2).Reply(200, "item")
+	mock.Expect("GET", "*/missing").AnyTimes().Reply(404, "")
+	mock.Expect("DELETE", "*").ReplyError(errors.New("connection reset"))
+	mock.Expect("PUT", "https://example.com/echo").ReplyFunc(func(req *http.Request, body []byte) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+	})
+	never := mock.Expect("PATCH", "*")
+
+	w := greqs.NewWorker("", 5*time.Second, nil, nil)
+	w.SetTransport(mock)
+	w.SetBaseUrl("https://example.com")
+	for i := 0; i < 2; i++ {
+		if resp, err := w.Get("/items/"+string(rune('1'+i)), nil); err != nil || resp.Text() != "item" {
+			t.Fatal(resp, err)
+		}
+	}
+	if resp, err := w.Get("/missing", nil); err != nil || resp.StatusCode != 404 {
+		t.Fatal(resp, err)
+	}
+	if _, err := w.Delete("/items/1", nil); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("err = %v", err)
+	}
+	if resp, err := w.Put("/echo", nil, greqs.A{"a": 1}); err != nil || resp.StatusCode != 200 {
+		t.Fatal(resp, err)
+	}
+	if w.GetTransport() != mock {
+		t.Error("GetTransport")
+	}
+
+	// PATCH 没有被调用
+	r := &recorder{TB: t}
+	mock.AssertExpectations(r)
+	if len(r.errors) != 1 || never.Calls() != 0 {
+		t.Errorf("errors = %v", r.errors)
+	}
+}
+
+func TestMock_Delay(t *testing.T) {
+	mock := NewMock()
+	mock.Install(t)
+	mock.Expect("GET", "https://example.com/slow").Times(2).Delay(50*time.Millisecond).Reply(200, "ok")
+
+	start := time.Now()
+	if resp, err := greqs.Get("https://example.com/slow", nil); err != nil || resp.Text() != "ok" {
+		t.Fatal(resp, err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("延迟 %s", d)
+	}
+
+	// 超时先于延迟触发
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := greqs.GetCtx(ctx, "https://example.com/slow", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, url string
+		want         bool
+	}{
+		{"https://a.com/x", "https://a.com/x?q=1", true},
+		{"https://a.com/x?q=1", "https://a.com/x?q=1", true},
+		{"https://a.com/x?q=*", "https://a.com/x?q=2", true},
+		{"https://a.com/x?q=1", "https://a.com/x", false},
+		{"https://a.com/*/x", "https://a.com/a/b/x", true},
+		{"https://a.com/*/x", "https://a.com/x", false},
+		{"*b*b", "xb", false},
+		{"*b*b", "xbb", true},
+	}
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.url); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v", tt.pattern, tt.url, got)
+		}
+	}
+}
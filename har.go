@@ -0,0 +1,506 @@
+package greqs
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	_url "net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR HTTP Archive 1.2 文件，浏览器开发者工具导出的格式
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+	Comment string      `json:"comment,omitempty"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry 一次请求和响应
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // 总耗时（毫秒），为 Timings 中非负值之和
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	Url         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+	Comment     string         `json:"comment,omitempty"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARCookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+type HARPostData struct {
+	MimeType string     `json:"mimeType"`
+	Params   []HARParam `json:"params,omitempty"`
+	Text     string     `json:"text"`
+}
+
+type HARParam struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	FileName    string `json:"fileName,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // 响应体不是 UTF-8 文本时为 base64
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings 各阶段耗时（毫秒），-1 表示不适用（例如复用连接时没有 DNS 和连接阶段）
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"` // 包括 SSL
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HARRecorder 记录请求和响应，通过 RecordHAR 中间件使用，可以被多个协程同时使用
+type HARRecorder struct {
+	mu      sync.Mutex
+	entries []*HAREntry
+}
+
+// NewHARRecorder 创建记录器
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// HAR 已记录的请求，按开始时间排序
+func (rec *HARRecorder) HAR() *HAR {
+	rec.mu.Lock()
+	entries := slices.Clone(rec.entries)
+	rec.mu.Unlock()
+	slices.SortStableFunc(entries, func(a, b *HAREntry) int { return a.StartedDateTime.Compare(b.StartedDateTime) })
+	return newHAR(entries)
+}
+
+// Len 已记录的请求数
+func (rec *HARRecorder) Len() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.entries)
+}
+
+// Reset 清空记录
+func (rec *HARRecorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.entries = nil
+}
+
+// Save 把已记录的请求保存为 HAR 文件
+func (rec *HARRecorder) Save(path string) error {
+	return rec.HAR().Save(path)
+}
+
+// RecordHAR 记录每个请求和响应（包括耗时）的中间件。作为第一个中间件时记录的是包括重试在内的整个过程，
+// 请求头取最后一次实际发送的请求（包括认证器添加的请求头）；流式响应不记录响应体
+func RecordHAR(rec *HARRecorder) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			if err := Rewindable(req); err != nil {
+				return nil, err
+			}
+			body, err := requestBody(req)
+			if err != nil {
+				return nil, err
+			}
+
+			timer := &harTimer{}
+			start := time.Now()
+			resp, err := next(req.WithContext(httptrace.WithClientTrace(req.Context(), timer.trace())))
+			end := time.Now()
+
+			entry := &HAREntry{StartedDateTime: start}
+			entry.Timings, entry.ServerIPAddress = timer.timings(start, end)
+			entry.Time = entry.Timings.total()
+			sent := req
+			if resp != nil && resp.Request != nil {
+				sent = resp.Request
+			}
+			entry.Request = harRequest(req, sent.Header, body)
+			entry.Response = HARResponse{Cookies: []HARCookie{}, Headers: []HARNameValue{}, HeadersSize: -1, BodySize: -1}
+			if resp != nil {
+				entry.Response = harResponse(resp)
+				entry.Request.HTTPVersion = entry.Response.HTTPVersion
+			}
+			if err != nil {
+				entry.Comment = err.Error()
+			}
+			rec.mu.Lock()
+			rec.entries = append(rec.entries, entry)
+			rec.mu.Unlock()
+			return resp, err
+		}
+	}
+}
+
+// harTimer 通过 httptrace 记录各阶段的时间点，重试时以最后一次尝试为准
+type harTimer struct {
+	mu sync.Mutex
+	t  harTimes
+}
+
+type harTimes struct {
+	getConn, dnsStart, dnsDone, connectStart time.Time
+	connectDone, tlsStart, tlsDone, gotConn  time.Time
+	wroteRequest, firstByte                  time.Time
+	remoteAddr                               string
+}
+
+func (t *harTimer) set(fn func(t *harTimes)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.t)
+}
+
+func (t *harTimer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn:           func(string) { t.set(func(t *harTimes) { *t = harTimes{getConn: time.Now()} }) },
+		DNSStart:          func(httptrace.DNSStartInfo) { t.set(func(t *harTimes) { t.dnsStart = time.Now() }) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { t.set(func(t *harTimes) { t.dnsDone = time.Now() }) },
+		ConnectStart:      func(string, string) { t.set(func(t *harTimes) { t.connectStart = time.Now() }) },
+		ConnectDone:       func(string, string, error) { t.set(func(t *harTimes) { t.connectDone = time.Now() }) },
+		TLSHandshakeStart: func() { t.set(func(t *harTimes) { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.set(func(t *harTimes) { t.tlsDone = time.Now() }) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.set(func(t *harTimes) {
+				t.gotConn = time.Now()
+				if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+					t.remoteAddr = host
+				}
+			})
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(func(t *harTimes) { t.wroteRequest = time.Now() }) },
+		GotFirstResponseByte: func() { t.set(func(t *harTimes) { t.firstByte = time.Now() }) },
+	}
+}
+
+// timings 计算各阶段耗时，没有经过网络（例如来自缓存或磁带）时全部计入 wait
+func (t *harTimer) timings(start, end time.Time) (HARTimings, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tm := &t.t
+	ms := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return -1
+		}
+		return float64(to.Sub(from).Microseconds()) / 1000
+	}
+	if tm.gotConn.IsZero() || tm.firstByte.IsZero() {
+		return HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: ms(start, end)}, tm.remoteAddr
+	}
+	timings := HARTimings{
+		DNS:     ms(tm.dnsStart, tm.dnsDone),
+		Connect: ms(tm.connectStart, tm.tlsDone),
+		SSL:     ms(tm.tlsStart, tm.tlsDone),
+		Send:    max(ms(tm.gotConn, tm.wroteRequest), 0),
+		Wait:    max(ms(tm.wroteRequest, tm.firstByte), 0),
+		Receive: max(ms(tm.firstByte, end), 0),
+	}
+	if tm.tlsDone.IsZero() {
+		timings.Connect = ms(tm.connectStart, tm.connectDone)
+	}
+	// 等待连接的时间，去掉 DNS 和建立连接的时间
+	timings.Blocked = ms(tm.getConn, tm.gotConn) - max(timings.DNS, 0) - max(timings.Connect, 0)
+	if timings.Blocked < 0 {
+		timings.Blocked = -1
+	}
+	return timings, tm.remoteAddr
+}
+
+func (t HARTimings) total() float64 {
+	total := 0.0
+	for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		total += max(v, 0)
+	}
+	return total
+}
+
+// harRequest 转换请求，header 为实际发送的请求头
+func harRequest(req *http.Request, header http.Header, body []byte) HARRequest {
+	r := HARRequest{
+		Method:      req.Method,
+		Url:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Cookies:     []HARCookie{},
+		Headers:     harHeaders(header),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		r.Headers = append([]HARNameValue{{"Host", req.Host}}, r.Headers...)
+	}
+	for _, c := range (&http.Request{Header: header}).Cookies() {
+		r.Cookies = append(r.Cookies, HARCookie{Name: c.Name, Value: c.Value})
+	}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			r.QueryString = append(r.QueryString, HARNameValue{name, v})
+		}
+	}
+	slices.SortStableFunc(r.QueryString, func(a, b HARNameValue) int { return strings.Compare(a.Name, b.Name) })
+	if len(body) > 0 {
+		mimeType := header.Get("Content-Type")
+		r.PostData = &HARPostData{MimeType: mimeType, Text: string(body)}
+		if !utf8.Valid(body) {
+			r.PostData.Text = ""
+		}
+		if mt, _, _ := mime.ParseMediaType(mimeType); mt == "application/x-www-form-urlencoded" {
+			if values, err := _url.ParseQuery(string(body)); err == nil {
+				for name, vs := range values {
+					for _, v := range vs {
+						r.PostData.Params = append(r.PostData.Params, HARParam{Name: name, Value: v})
+					}
+				}
+				slices.SortStableFunc(r.PostData.Params, func(a, b HARParam) int { return strings.Compare(a.Name, b.Name) })
+			}
+		}
+	}
+	return r
+}
+
+func harResponse(resp *Response) HARResponse {
+	r := HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []HARCookie{},
+		Headers:     harHeaders(resp.Header),
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    int64(len(resp.Body)),
+	}
+	for _, c := range resp.Cookies() {
+		cookie := HARCookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, HTTPOnly: c.HttpOnly, Secure: c.Secure}
+		if !c.Expires.IsZero() {
+			cookie.Expires = &c.Expires
+		}
+		r.Cookies = append(r.Cookies, cookie)
+	}
+	r.Content = HARContent{Size: int64(len(resp.Body)), MimeType: resp.Header.Get("Content-Type")}
+	switch {
+	case resp.IsStream():
+		r.BodySize = -1
+		r.Content.Size = -1
+		r.Content.Comment = "流式响应，没有记录响应体"
+	case utf8.Valid(resp.Body):
+		r.Content.Text = string(resp.Body)
+	default:
+		r.Content.Text, r.Content.Encoding = base64.StdEncoding.EncodeToString(resp.Body), "base64"
+	}
+	return r
+}
+
+// harHeaders 按名称排序的请求头或响应头
+func harHeaders(h http.Header) []HARNameValue {
+	out := []HARNameValue{}
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		for _, v := range h[name] {
+			out = append(out, HARNameValue{name, v})
+		}
+	}
+	return out
+}
+
+func newHAR(entries []*HAREntry) *HAR {
+	if entries == nil {
+		entries = []*HAREntry{}
+	}
+	return &HAR{Log: HARLog{Version: "1.2", Creator: HARCreator{Name: "greqs", Version: "(devel)"}, Entries: entries}}
+}
+
+// Save 保存为 HAR 文件
+func (h *HAR) Save(path string) error {
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// ReadHAR 解析 HAR
+func ReadHAR(r io.Reader) (*HAR, error) {
+	var h HAR
+	if err := json.NewDecoder(r).Decode(&h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// LoadHAR 读取 HAR 文件
+func LoadHAR(path string) (*HAR, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadHAR(f)
+}
+
+// HARFilter 导入 HAR 时的过滤条件，字段为空时不过滤
+type HARFilter struct {
+	Url     *regexp.Regexp // 网址需要匹配的正则表达式
+	Methods []string       // 允许的请求方法
+}
+
+func (f *HARFilter) match(r *HARRequest) bool {
+	if f == nil {
+		return true
+	}
+	if f.Url != nil && !f.Url.MatchString(r.Url) {
+		return false
+	}
+	return len(f.Methods) == 0 || slices.ContainsFunc(f.Methods, func(m string) bool { return strings.EqualFold(m, r.Method) })
+}
+
+// 导入时忽略的请求头：HTTP/2 伪首部以外，这些请求头由 Transport 根据实际请求生成。
+// Accept-Encoding 交给 Transport 处理，才能自动解压 gzip 响应
+var harSkipHeaders = map[string]bool{"Host": true, "Content-Length": true, "Connection": true, "Accept-Encoding": true}
+
+// Requests 把 HAR 中的请求转换为 Request，可以通过 Batch 重新执行
+func (h *HAR) Requests(filter *HARFilter) ([]*Request, error) {
+	var reqs []*Request
+	for i, entry := range h.Log.Entries {
+		if entry == nil || !filter.match(&entry.Request) {
+			continue
+		}
+		r, err := entry.Request.toRequest()
+		if err != nil {
+			return nil, fmt.Errorf("转换 HAR 第 %d 个请求失败: %w", i+1, err)
+		}
+		reqs = append(reqs, r)
+	}
+	return reqs, nil
+}
+
+func (hr *HARRequest) toRequest() (*Request, error) {
+	method, err := CheckMethod(hr.Method)
+	if err != nil {
+		return nil, err
+	}
+	r := &Request{Method: method, Url: hr.Url, Headers: S{}}
+	for _, h := range hr.Headers {
+		name := http.CanonicalHeaderKey(h.Name)
+		if strings.HasPrefix(h.Name, ":") || harSkipHeaders[name] {
+			continue
+		}
+		if old, ok := r.Headers[name]; ok {
+			sep := ", "
+			if name == "Cookie" {
+				sep = "; "
+			}
+			h.Value = old + sep + h.Value
+		}
+		r.Headers[name] = h.Value
+	}
+	if _, ok := r.Headers["Cookie"]; !ok && len(hr.Cookies) > 0 {
+		cookies := make([]string, len(hr.Cookies))
+		for i, c := range hr.Cookies {
+			cookies[i] = c.Name + "=" + c.Value
+		}
+		r.Headers["Cookie"] = strings.Join(cookies, "; ")
+	}
+
+	p := hr.PostData
+	if p == nil {
+		return r, nil
+	}
+	contentType := p.MimeType
+	if contentType == "" {
+		contentType = r.Headers["Content-Type"]
+	}
+	delete(r.Headers, "Content-Type")
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case p.Text != "":
+		r.Body, r.ContentType = []byte(p.Text), contentType
+	case len(p.Params) > 0 && mt == "multipart/form-data":
+		// 没有原始请求体时按字段重新编码
+		m := NewMultipart()
+		for _, param := range p.Params {
+			if param.FileName != "" {
+				m.AddBytes(param.Name, param.FileName, []byte(param.Value), param.ContentType)
+			} else {
+				m.AddField(param.Name, param.Value)
+			}
+		}
+		r.Multipart = m
+	case len(p.Params) > 0:
+		values := _url.Values{}
+		for _, param := range p.Params {
+			values.Add(param.Name, param.Value)
+		}
+		r.Body, r.ContentType = []byte(values.Encode()), contentType
+	}
+	return r, nil
+}
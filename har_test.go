@@ -0,0 +1,186 @@
+package greqs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordHAR(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc", Path: "/", HttpOnly: true})
+		w.Write([]byte{0xff, 0xfe})
+	}))
+	defer tlsSrv.Close()
+
+	rec := NewHARRecorder()
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(RecordHAR(rec))
+	w.SetTLS(&TLSOptions{InsecureSkipVerify: true})
+	w.SetAuth(&BearerAuth{Token: "t0k3n"})
+
+	if _, err := w.Post(srv.URL+"/items?page=1&q=go", S{"Cookie": "a=1; b=2"}, A{"name": "Greqs"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.PostForm(srv.URL+"/form", nil, S{"x": "1", "y": "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Get(tlsSrv.URL+"/bin", nil); err != nil {
+		t.Fatal(err)
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	if _, err := w.Get(closed.URL, nil); err == nil {
+		t.Fatal("请求已关闭的服务应该失败")
+	}
+
+	har := rec.HAR()
+	if har.Log.Version != "1.2" || har.Log.Creator.Name != "greqs" || len(har.Log.Entries) != 4 {
+		t.Fatalf("%+v", har.Log)
+	}
+
+	post := har.Log.Entries[0]
+	req := post.Request
+	if req.Method != "POST" || req.Url != srv.URL+"/items?page=1&q=go" || req.HTTPVersion != "HTTP/1.1" ||
+		len(req.QueryString) != 2 || req.QueryString[0] != (HARNameValue{"page", "1"}) || len(req.Cookies) != 2 {
+		t.Errorf("请求 %+v", req)
+	}
+	if req.PostData == nil || req.PostData.Text != `{"name":"Greqs"}` || req.PostData.MimeType != "application/json" || req.BodySize != 16 {
+		t.Errorf("请求体 %+v", req.PostData)
+	}
+	// 认证器添加的请求头也会被记录
+	if !hasHARHeader(req.Headers, "Authorization", "Bearer t0k3n") {
+		t.Errorf("请求头 %v", req.Headers)
+	}
+	resp := post.Response
+	if resp.Status != 200 || resp.StatusText != "OK" || resp.Content.Text != `{"name":"Greqs"}` || !hasHARHeader(resp.Headers, "X-Method", "POST") {
+		t.Errorf("响应 %+v", resp)
+	}
+	if post.ServerIPAddress != "127.0.0.1" || post.Time <= 0 || post.Timings.Connect < 0 || post.Timings.SSL != -1 || post.Timings.Wait < 0 {
+		t.Errorf("耗时 %+v %s %v", post.Timings, post.ServerIPAddress, post.Time)
+	}
+
+	form := har.Log.Entries[1].Request.PostData
+	if form == nil || len(form.Params) != 2 || form.Params[1] != (HARParam{Name: "y", Value: "2"}) {
+		t.Errorf("表单 %+v", form)
+	}
+
+	bin := har.Log.Entries[2]
+	if bin.Response.Content.Encoding != "base64" || bin.Response.Content.Text != "//4=" || bin.Timings.SSL < 0 ||
+		len(bin.Response.Cookies) != 1 || !bin.Response.Cookies[0].HTTPOnly {
+		t.Errorf("HTTPS %+v %+v", bin.Response, bin.Timings)
+	}
+
+	failed := har.Log.Entries[3]
+	if failed.Response.Status != 0 || failed.Comment == "" || failed.Response.Headers == nil {
+		t.Errorf("失败的请求 %+v", failed)
+	}
+
+	rec.Reset()
+	if rec.Len() != 0 {
+		t.Errorf("Len = %d", rec.Len())
+	}
+}
+
+func TestHAR_Replay(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	// 录制后保存，再读取并通过 Batch 重新执行
+	rec := NewHARRecorder()
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(RecordHAR(rec))
+	w.Post(srv.URL+"/a", S{"X-Id": "1"}, A{"n": 1})
+	w.Put(srv.URL+"/b", nil, A{"n": 2})
+	w.PostForm(srv.URL+"/a", nil, S{"n": "3"})
+	w.Get("http://127.0.0.1:1/other", nil)
+	path := t.TempDir() + "/traffic.har"
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	har, err := LoadHAR(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs, err := har.Requests(&HARFilter{Url: regexp.MustCompile(`^` + regexp.QuoteMeta(srv.URL)), Methods: []string{"post", "PUT"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 3 {
+		t.Fatalf("导入了 %d 个请求", len(reqs))
+	}
+	if reqs[0].Headers["X-Id"] != "1" || reqs[0].ContentType != "application/json" {
+		t.Errorf("%+v", reqs[0])
+	}
+
+	results, err := Batch(context.Background(), reqs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`POST application/json {"n":1}`, `PUT application/json {"n":2}`, `POST application/x-www-form-urlencoded n=3`}
+	for i, res := range results {
+		got := res.Response.Header.Get("X-Method") + " " + res.Response.Header.Get("X-Content-Type") + " " + res.Response.Text()
+		if got != want[i] {
+			t.Errorf("%d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestHAR_Import(t *testing.T) {
+	// 浏览器导出的 HAR（HTTP/2 伪首部、没有原始请求体的表单）
+	const data = `{"log": {"version": "1.2", "creator": {"name": "WebInspector", "version": "537.36"}, "entries": [
+	{"request": {"method": "POST", "url": "https://example.com/login", "httpVersion": "http/2.0",
+		"headers": [{"name": ":authority", "value": "example.com"}, {"name": "accept-encoding", "value": "gzip, br"},
+			{"name": "content-type", "value": "application/x-www-form-urlencoded"}, {"name": "accept", "value": "text/html"},
+			{"name": "accept", "value": "*/*"}, {"name": "content-length", "value": "7"}],
+		"cookies": [{"name": "a", "value": "1"}, {"name": "b", "value": "2"}],
+		"postData": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "u", "value": "tom"}, {"name": "p", "value": "x y"}]}}},
+	{"request": {"method": "POST", "url": "https://example.com/upload", "headers": [],
+		"postData": {"mimeType": "multipart/form-data; boundary=xyz", "params": [{"name": "title", "value": "hi"}, {"name": "file", "fileName": "a.txt", "value": "hello", "contentType": "text/plain"}]}}},
+	{"request": {"method": "GET", "url": "https://cdn.example.com/app.js", "headers": []}},
+	{"request": {"method": "BREW", "url": "https://example.com/coffee", "headers": []}}
+	]}}`
+	har, err := ReadHAR(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs, err := har.Requests(&HARFilter{Url: regexp.MustCompile(`//example\.com/`), Methods: []string{"POST"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("导入了 %d 个请求", len(reqs))
+	}
+	login := reqs[0]
+	if len(login.Headers) != 2 || login.Headers["Accept"] != "text/html, */*" || login.Headers["Cookie"] != "a=1; b=2" {
+		t.Errorf("请求头 %v", login.Headers)
+	}
+	if string(login.Body) != "p=x+y&u=tom" || login.ContentType != "application/x-www-form-urlencoded" {
+		t.Errorf("请求体 %q %s", login.Body, login.ContentType)
+	}
+	upload := reqs[1]
+	if m := upload.Multipart; m == nil || len(m.fields) != 1 || len(m.files) != 1 || m.files[0].Filename != "a.txt" || !m.Replayable() {
+		t.Errorf("multipart %+v", upload.Multipart)
+	}
+
+	// 不支持的方法返回错误
+	if _, err := har.Requests(nil); err == nil || !strings.Contains(err.Error(), "第 4 个") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func hasHARHeader(headers []HARNameValue, name, value string) bool {
+	for _, h := range headers {
+		if h.Name == name && h.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package greqs
+
+import (
+	"greqs/log"
+	"net/http"
+	"time"
+)
+
+// Handler 发送请求并返回响应
+type Handler func(req *http.Request) (*Response, error)
+
+// Middleware 中间件，包裹下一个处理函数。
+// 中间件可以修改请求、查看或替换响应、多次调用 next 进行重试，也可以不调用 next 直接返回（拦截请求）。
+type Middleware func(next Handler) Handler
+
+// Chain 把中间件按顺序组合到 h 上，第一个中间件在最外层
+func Chain(h Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// RequestHook 把只修改请求的钩子函数转换为中间件
+func RequestHook(hook func(req *http.Request)) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			hook(req)
+			return next(req)
+		}
+	}
+}
+
+// DefaultHeaders 补充默认请求头，请求中已有的请求头不会被覆盖
+func DefaultHeaders(headers S) Middleware {
+	return RequestHook(func(req *http.Request) {
+		for key, val := range headers {
+			if req.Header.Get(key) == "" {
+				req.Header.Set(key, val)
+			}
+		}
+	})
+}
+
+// UserAgent 设置 User-Agent，请求中已有时不会被覆盖
+func UserAgent(ua string) Middleware {
+	return DefaultHeaders(S{"User-Agent": ua})
+}
+
+// Logging 打印每个请求的方法、网址、状态码和耗时
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			cost := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				log.Error("%s %s | %v | %s", req.Method, req.URL, err, cost)
+			} else {
+				log.Info("%s %s | %d | %s", req.Method, req.URL, resp.StatusCode, cost)
+			}
+			return resp, err
+		}
+	}
+}
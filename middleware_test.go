@@ -0,0 +1,64 @@
+package greqs
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorker_Use(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *http.Request) (*Response, error) {
+				order = append(order, name+">")
+				resp, err := next(req)
+				order = append(order, "<"+name)
+				return resp, err
+			}
+		}
+	}
+
+	worker := NewWorker("", 5*time.Second, m1, nil)
+	worker.Use(trace("a"), trace("b"), UserAgent("Ignored"), DefaultHeaders(S{"X-Token": "abc"}), Logging())
+	resp, err := worker.Get(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, " "); got != "a> b> <b <a" {
+		t.Errorf("order = %s", got)
+	}
+	if ua := resp.Request.Header.Get("User-Agent"); ua != "Greqs/0.0.1" {
+		t.Errorf("User-Agent = %s, reqHook should run first", ua)
+	}
+	if resp.Request.Header.Get("X-Token") != "abc" {
+		t.Error("default header missing")
+	}
+}
+
+func TestWorker_UseReject(t *testing.T) {
+	srv, count := newFlakyServer(0)
+	defer srv.Close()
+
+	errBlocked := errors.New("blocked")
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.Use(func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			if req.Method == "DELETE" {
+				return nil, errBlocked
+			}
+			return next(req)
+		}
+	})
+	if _, err := worker.Delete(srv.URL, nil); !errors.Is(err, errBlocked) {
+		t.Errorf("err = %v, want blocked", err)
+	}
+	if *count != 0 {
+		t.Errorf("request reached server %d times", *count)
+	}
+}
@@ -0,0 +1,192 @@
+package greqs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// File 上传的文件，Path、Data 和 Reader 三选一
+type File struct {
+	Field       string    // 表单字段名
+	Filename    string    // 文件名，为空时使用 Path 的文件名
+	Path        string    // 磁盘上的文件路径
+	Data        []byte    // 内存中的文件内容，可以重复编码
+	Reader      io.Reader // 文件内容，只能读取一次
+	ContentType string    // 文件类型，为空时根据扩展名推断
+}
+
+// Multipart 多部分表单，编码时通过管道边读边写，大文件不会整体读入内存
+type Multipart struct {
+	fields   [][2]string
+	files    []File
+	boundary string // 创建时生成的固定分隔符，保证重复编码时与 Content-Type 一致，并发编码时只读
+}
+
+// NewMultipart 创建多部分表单
+func NewMultipart() *Multipart {
+	return &Multipart{boundary: multipart.NewWriter(io.Discard).Boundary()}
+}
+
+// AddField 添加普通字段
+func (m *Multipart) AddField(name, value string) *Multipart {
+	m.fields = append(m.fields, [2]string{name, value})
+	return m
+}
+
+// AddFields 批量添加普通字段
+func (m *Multipart) AddFields(fields S) *Multipart {
+	for name, value := range fields {
+		m.AddField(name, value)
+	}
+	return m
+}
+
+// AddFile 添加磁盘上的文件，同一字段可以添加多个文件
+func (m *Multipart) AddFile(field, path string) *Multipart {
+	m.files = append(m.files, File{Field: field, Path: path})
+	return m
+}
+
+// AddReader 添加来自 io.Reader 的文件
+func (m *Multipart) AddReader(field, filename string, r io.Reader, contentType string) *Multipart {
+	m.files = append(m.files, File{Field: field, Filename: filename, Reader: r, ContentType: contentType})
+	return m
+}
+
+// AddBytes 添加内存中的文件，与 AddReader 不同，重试时可以重新编码
+func (m *Multipart) AddBytes(field, filename string, data []byte, contentType string) *Multipart {
+	m.files = append(m.files, File{Field: field, Filename: filename, Data: data, ContentType: contentType})
+	return m
+}
+
+// Add 添加文件
+func (m *Multipart) Add(files ...File) *Multipart {
+	m.files = append(m.files, files...)
+	return m
+}
+
+// Replayable 是否可以重复编码（所有文件都来自磁盘或内存时可以，重试时会重新读取文件）
+func (m *Multipart) Replayable() bool {
+	for _, f := range m.files {
+		if f.Reader != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Reader 返回编码后的请求体和请求体类型，第一次读取时才在后台协程中编码，
+// 请求没有发送（如中间件直接返回响应）时不会启动协程或打开文件
+func (m *Multipart) Reader() (io.ReadCloser, string) {
+	boundary := m.boundary
+	if boundary == "" {
+		boundary = multipart.NewWriter(io.Discard).Boundary()
+	}
+	return &lazyBody{m: m, boundary: boundary}, "multipart/form-data; boundary=" + boundary
+}
+
+// lazyBody 延迟编码的请求体
+type lazyBody struct {
+	mu       sync.Mutex
+	m        *Multipart
+	boundary string
+	pr       *io.PipeReader
+	closed   bool
+}
+
+func (b *lazyBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	if b.pr == nil {
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		writer.SetBoundary(b.boundary)
+		go func() {
+			pw.CloseWithError(b.m.write(writer))
+		}()
+		b.pr = pr
+	}
+	pr := b.pr
+	b.mu.Unlock()
+	return pr.Read(p)
+}
+
+func (b *lazyBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	if b.pr != nil {
+		return b.pr.Close()
+	}
+	return nil
+}
+
+// write 写入所有字段和文件
+func (m *Multipart) write(writer *multipart.Writer) error {
+	for _, field := range m.fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	for _, f := range m.files {
+		if err := writeFile(writer, f); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// writeFile 写入一个文件
+func writeFile(writer *multipart.Writer, f File) error {
+	r := f.Reader
+	if f.Data != nil {
+		r = bytes.NewReader(f.Data)
+	}
+	if r == nil {
+		file, err := os.Open(f.Path)
+		if err != nil {
+			return fmt.Errorf("打开文件失败: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	filename := f.Filename
+	if filename == "" {
+		filename = filepath.Base(f.Path)
+	}
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(f.Field), escapeQuotes(filename)))
+	h.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, r)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,147 @@
+package greqs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 本地上传服务，返回收到的字段和文件（字段名:文件名:类型:内容）
+func newUploadServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var parts []string
+		for key, vals := range r.MultipartForm.Value {
+			parts = append(parts, key+"="+strings.Join(vals, ","))
+		}
+		for field, files := range r.MultipartForm.File {
+			for _, fh := range files {
+				f, _ := fh.Open()
+				b, _ := io.ReadAll(f)
+				f.Close()
+				parts = append(parts, fmt.Sprintf("%s:%s:%s:%s", field, fh.Filename, fh.Header.Get("Content-Type"), b))
+			}
+		}
+		sort.Strings(parts)
+		w.Write([]byte(strings.Join(parts, "|")))
+	}))
+}
+
+func TestMultipart(t *testing.T) {
+	srv := newUploadServer()
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "data.csv")
+	os.WriteFile(path, []byte("a,b\n1,2\n"), 0644)
+
+	form := NewMultipart().
+		AddField("name", "Greqs").
+		AddFile("files", path).
+		AddReader("files", "logo.png", strings.NewReader("PNG"), "").
+		AddReader("note", `a"b.txt`, strings.NewReader("hi"), "text/custom")
+	resp, err := (&Request{Method: "POST", Url: srv.URL, Multipart: form}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `files:data.csv:text/csv; charset=utf-8:a,b` + "\n1,2\n" + `|files:logo.png:image/png:PNG|name=Greqs|note:a"b.txt:text/custom:hi`
+	if resp.Text() != want {
+		t.Errorf("got  %q\nwant %q", resp.Text(), want)
+	}
+}
+
+func TestMultipart_Retry(t *testing.T) {
+	var count int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if count++; count == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		r.ParseMultipartForm(1 << 20)
+		fh := r.MultipartForm.File["file"][0]
+		f, _ := fh.Open()
+		io.Copy(w, f)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(path, []byte("content"), 0644)
+
+	// 磁盘和内存中的文件都可以在重试时重新编码
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetRetry(&RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	for _, form := range []*Multipart{
+		NewMultipart().AddFile("file", path),
+		NewMultipart().AddBytes("file", "a.txt", []byte("content"), "text/plain"),
+	} {
+		count = 0
+		resp, err := worker.SendBody(t.Context(), "POST", srv.URL, nil, &Body{Multipart: form})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Text() != "content" || count != 2 {
+			t.Errorf("body = %q, count = %d", resp.Text(), count)
+		}
+	}
+}
+
+func TestMultipart_Concurrent(t *testing.T) {
+	srv := newUploadServer()
+	defer srv.Close()
+
+	// 多个请求共用同一个表单并发编码
+	form := NewMultipart().AddBytes("file", "a.txt", []byte("content"), "text/plain")
+	var reqs []*Request
+	for i := 0; i < 8; i++ {
+		reqs = append(reqs, &Request{Method: "POST", Url: srv.URL, Multipart: form})
+	}
+	results, err := Batch(t.Context(), reqs, &BatchOptions{Workers: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		if res.Err != nil || res.Response.Text() != "file:a.txt:text/plain:content" {
+			t.Errorf("result = %+v", res)
+		}
+	}
+}
+
+func TestMultipart_NotSent(t *testing.T) {
+	// 中间件直接返回，请求体没有被读取，也不应该留下编码协程
+	reject := func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			return nil, fmt.Errorf("rejected")
+		}
+	}
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		form := NewMultipart().AddBytes("file", "a.txt", []byte("content"), "text/plain")
+		req := &Request{Method: "POST", Url: "http://127.0.0.1:1", Multipart: form, Middlewares: []Middleware{reject}}
+		if _, err := req.Do(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if n := runtime.NumGoroutine() - before; n >= 10 {
+		t.Errorf("多出 %d 个协程", n)
+	}
+}
+
+func TestMultipart_MissingFile(t *testing.T) {
+	srv := newUploadServer()
+	defer srv.Close()
+
+	form := NewMultipart().AddFile("file", filepath.Join(t.TempDir(), "missing"))
+	if _, err := SendPostRequest(srv.URL, &Options{Multipart: form}); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,218 @@
+package greqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	_url "net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultExpiryDelta 令牌在过期前多久刷新
+var DefaultExpiryDelta = 30 * time.Second
+
+// Token OAuth2 访问令牌
+type Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Expiry       time.Time // 为零值时表示不过期
+}
+
+// header Authorization 请求头的值
+func (t *Token) header() string {
+	typ := t.TokenType
+	if typ == "" || strings.EqualFold(typ, "bearer") {
+		typ = "Bearer"
+	}
+	return typ + " " + t.AccessToken
+}
+
+// OAuth2Error 令牌端点返回的错误（RFC 6749 5.2）
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+func (e *OAuth2Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("获取令牌失败: %d %s: %s", e.StatusCode, e.Code, e.Description)
+	}
+	return fmt.Sprintf("获取令牌失败: %d %s", e.StatusCode, e.Code)
+}
+
+// OAuth2 从令牌端点获取访问令牌的认证器，RefreshToken 不为空时使用 refresh_token 授权，否则使用 client_credentials 授权。
+// 令牌会被缓存，在过期前 ExpiryDelta 或收到 401 时刷新，多个协程同时使用时只会有一个请求去获取令牌
+type OAuth2 struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RefreshToken string        // 刷新令牌，令牌端点返回新的刷新令牌时会被替换
+	Params       S             // 额外的参数，例如 audience
+	AuthInBody   bool          // 客户端凭证放在请求体中，默认使用 Basic 认证
+	ExpiryDelta  time.Duration // 提前刷新的时间，0 表示使用 DefaultExpiryDelta
+	Client       *http.Client  // 请求令牌端点的客户端，为空时使用共享客户端
+
+	mu      sync.Mutex
+	token   *Token
+	pending chan struct{} // 正在获取令牌时不为空，获取结束后关闭
+}
+
+// Authenticate 添加 Authorization 请求头，令牌不可用时先获取令牌
+func (o *OAuth2) Authenticate(req *http.Request) error {
+	token, err := o.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", token.header())
+	return nil
+}
+
+// Challenge 收到 401 时丢弃发送请求时使用的令牌，请求会带着新令牌再发送一次
+func (o *OAuth2) Challenge(req *http.Request, resp *Response) (bool, error) {
+	if resp.Request == nil {
+		return false, nil
+	}
+	used := resp.Request.Header.Get("Authorization")
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// 其他协程已经刷新过令牌时不再丢弃
+	if o.token != nil && o.token.header() == used {
+		o.token = nil
+	}
+	return true, nil
+}
+
+// Token 获取有效的令牌
+func (o *OAuth2) Token(ctx context.Context) (*Token, error) {
+	for {
+		o.mu.Lock()
+		if o.valid(o.token) {
+			token := o.token
+			o.mu.Unlock()
+			return token, nil
+		}
+		if wait := o.pending; wait != nil {
+			o.mu.Unlock()
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		wait := make(chan struct{})
+		o.pending = wait
+		refresh := o.RefreshToken
+		o.mu.Unlock()
+
+		token, err := o.fetch(ctx, refresh)
+
+		o.mu.Lock()
+		o.pending = nil
+		if err == nil {
+			o.token = token
+			if token.RefreshToken != "" {
+				o.RefreshToken = token.RefreshToken
+			}
+		}
+		o.mu.Unlock()
+		close(wait)
+		return token, err
+	}
+}
+
+// SetToken 设置令牌，例如从缓存中恢复
+func (o *OAuth2) SetToken(token *Token) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.token = token
+	if token != nil && token.RefreshToken != "" {
+		o.RefreshToken = token.RefreshToken
+	}
+}
+
+// valid 令牌存在且不会在 ExpiryDelta 内过期（调用方需持有锁）
+func (o *OAuth2) valid(t *Token) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	delta := o.ExpiryDelta
+	if delta == 0 {
+		delta = DefaultExpiryDelta
+	}
+	return t.Expiry.IsZero() || time.Now().Add(delta).Before(t.Expiry)
+}
+
+// fetch 请求令牌端点
+func (o *OAuth2) fetch(ctx context.Context, refresh string) (*Token, error) {
+	form := S{"grant_type": "client_credentials"}
+	if refresh != "" {
+		form = S{"grant_type": "refresh_token", "refresh_token": refresh}
+	}
+	if len(o.Scopes) > 0 {
+		form["scope"] = strings.Join(o.Scopes, " ")
+	}
+	for key, val := range o.Params {
+		form[key] = val
+	}
+	if o.AuthInBody {
+		form["client_id"] = o.ClientID
+		if o.ClientSecret != "" {
+			form["client_secret"] = o.ClientSecret
+		}
+	}
+
+	req, err := MakeBodyRequest(http.MethodPost, o.TokenURL, S{"Accept": "application/json"}, &Body{Form: form})
+	if err != nil {
+		return nil, err
+	}
+	if !o.AuthInBody && o.ClientID != "" {
+		// RFC 6749 2.3.1：客户端标识和密钥先按 application/x-www-form-urlencoded 编码
+		req.SetBasicAuth(_url.QueryEscape(o.ClientID), _url.QueryEscape(o.ClientSecret))
+	}
+	cli := o.Client
+	if cli == nil {
+		if cli, err = sharedClient("", 0, nil); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := Do(cli, req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("获取令牌失败: %w", err)
+	}
+
+	var data struct {
+		AccessToken  string      `json:"access_token"`
+		TokenType    string      `json:"token_type"`
+		RefreshToken string      `json:"refresh_token"`
+		ExpiresIn    json.Number `json:"expires_in"`
+		Error        string      `json:"error"`
+		Description  string      `json:"error_description"`
+	}
+	decodeErr := resp.Decode(&data)
+	if resp.StatusCode != http.StatusOK || data.Error != "" {
+		e := &OAuth2Error{StatusCode: resp.StatusCode, Code: data.Error, Description: data.Description}
+		if e.Code == "" {
+			e.Code = http.StatusText(resp.StatusCode)
+		}
+		return nil, e
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("解析令牌失败: %w", decodeErr)
+	}
+	if data.AccessToken == "" {
+		return nil, fmt.Errorf("令牌端点没有返回 access_token")
+	}
+
+	token := &Token{AccessToken: data.AccessToken, TokenType: data.TokenType, RefreshToken: data.RefreshToken}
+	if secs, err := data.ExpiresIn.Int64(); err == nil && secs > 0 {
+		token.Expiry = time.Now().Add(time.Duration(secs) * time.Second)
+	}
+	return token, nil
+}
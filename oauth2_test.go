@@ -0,0 +1,213 @@
+package greqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 本地令牌端点与受保护的接口，令牌依次为 token-1、token-2 ……
+type tokenServer struct {
+	*httptest.Server
+	expiresIn int64 // 令牌有效期（秒）
+	issued    int32 // 颁发的令牌数
+	revoked   int32 // 小于等于该序号的令牌被接口拒绝
+	grants    chan map[string]string
+}
+
+func newTokenServer(t *testing.T, expiresIn int64) *tokenServer {
+	s := &tokenServer{expiresIn: expiresIn, grants: make(chan map[string]string, 64)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form := map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		if user, pass, ok := r.BasicAuth(); ok {
+			form["basic"] = user + ":" + pass
+		}
+		s.grants <- form
+
+		w.Header().Set("Content-Type", "application/json")
+		if form["basic"] != "app:secret" && form["client_secret"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client","error_description":"bad secret"}`))
+			return
+		}
+		if form["grant_type"] == "refresh_token" && form["refresh_token"] == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		time.Sleep(10 * time.Millisecond) // 让并发请求有机会同时等待
+		n := atomic.AddInt32(&s.issued, 1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  fmt.Sprintf("token-%d", n),
+			"token_type":    "bearer",
+			"expires_in":    s.expiresIn,
+			"refresh_token": fmt.Sprintf("refresh-%d", n),
+		})
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		var n int32
+		if _, err := fmt.Sscanf(r.Header.Get("Authorization"), "Bearer token-%d", &n); err != nil || n <= atomic.LoadInt32(&s.revoked) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestOAuth2_ClientCredentials(t *testing.T) {
+	srv := newTokenServer(t, 3600)
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.SetAuth(&OAuth2{
+		TokenURL:     srv.URL + "/token",
+		ClientID:     "app",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+		Params:       S{"audience": "api"},
+	})
+
+	// 多个协程同时请求，只获取一次令牌
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := w.Get(srv.URL+"/api", nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if resp.Text() != "Bearer token-1" {
+				t.Errorf("got %d %q", resp.StatusCode, resp.Text())
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&srv.issued); n != 1 {
+		t.Fatalf("颁发了 %d 个令牌", n)
+	}
+	grant := <-srv.grants
+	if grant["grant_type"] != "client_credentials" || grant["scope"] != "read write" || grant["audience"] != "api" || grant["basic"] != "app:secret" {
+		t.Errorf("令牌请求 %v", grant)
+	}
+}
+
+func TestOAuth2_BasicAuthEscape(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		w.Write([]byte(`{"access_token":"t","token_type":"bearer"}`))
+	}))
+	defer srv.Close()
+
+	// 客户端标识和密钥中的特殊字符先做表单编码再放入 Basic 认证
+	auth := &OAuth2{TokenURL: srv.URL, ClientID: "app:1", ClientSecret: "s p&c"}
+	if _, err := auth.fetch(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", got)
+	if user, pass, _ := req.BasicAuth(); user != "app%3A1" || pass != "s+p%26c" {
+		t.Errorf("basic = %q:%q", user, pass)
+	}
+}
+
+func TestOAuth2_RefreshBeforeExpiry(t *testing.T) {
+	// 有效期 1 秒，提前 30 秒刷新，每次请求都会刷新
+	srv := newTokenServer(t, 1)
+	auth := &OAuth2{TokenURL: srv.URL + "/token", ClientID: "app", ClientSecret: "secret", RefreshToken: "refresh-0"}
+	for i := 1; i <= 3; i++ {
+		resp, err := SendContext(context.Background(), "GET", srv.URL+"/api", &Options{Auth: auth})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("Bearer token-%d", i); resp.Text() != want {
+			t.Fatalf("got %q, want %q", resp.Text(), want)
+		}
+		grant := <-srv.grants
+		// 刷新令牌会被替换为令牌端点返回的新值
+		if want := fmt.Sprintf("refresh-%d", i-1); grant["grant_type"] != "refresh_token" || grant["refresh_token"] != want {
+			t.Fatalf("令牌请求 %v", grant)
+		}
+	}
+
+	// 在有效期内复用令牌
+	auth.ExpiryDelta = time.Millisecond
+	auth.SetToken(&Token{AccessToken: "token-3", Expiry: time.Now().Add(time.Hour)})
+	if _, err := SendContext(context.Background(), "GET", srv.URL+"/api", &Options{Auth: auth}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&srv.issued); n != 3 {
+		t.Fatalf("颁发了 %d 个令牌", n)
+	}
+}
+
+func TestOAuth2_RefreshOn401(t *testing.T) {
+	srv := newTokenServer(t, 3600)
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.SetAuth(&OAuth2{TokenURL: srv.URL + "/token", ClientID: "app", ClientSecret: "secret", AuthInBody: true})
+
+	if resp, err := w.Get(srv.URL+"/api", nil); err != nil || resp.Text() != "Bearer token-1" {
+		t.Fatal(resp, err)
+	}
+	if grant := <-srv.grants; grant["client_id"] != "app" || grant["client_secret"] != "secret" || grant["basic"] != "" {
+		t.Errorf("令牌请求 %v", grant)
+	}
+
+	// 服务端吊销令牌，并发请求都收到 401，只会刷新一次
+	atomic.StoreInt32(&srv.revoked, 1)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := w.Get(srv.URL+"/api", nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if resp.Text() != "Bearer token-2" {
+				t.Errorf("got %d %q", resp.StatusCode, resp.Text())
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&srv.issued); n != 2 {
+		t.Fatalf("颁发了 %d 个令牌", n)
+	}
+}
+
+func TestOAuth2_Error(t *testing.T) {
+	srv := newTokenServer(t, 3600)
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.SetAuth(&OAuth2{TokenURL: srv.URL + "/token", ClientID: "app", ClientSecret: "wrong"})
+
+	_, err := w.Get(srv.URL+"/api", nil)
+	var oe *OAuth2Error
+	if !errors.As(err, &oe) {
+		t.Fatalf("err = %v", err)
+	}
+	if oe.StatusCode != http.StatusUnauthorized || oe.Code != "invalid_client" || oe.Description != "bad secret" {
+		t.Errorf("%+v", oe)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := w.GetCtx(ctx, srv.URL+"/api", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,468 @@
+package greqs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+)
+
+// PKCS#12 (RFC 7292) 解码，只实现客户端证书需要的部分：
+// 证书袋、私钥袋、PBES2（PBKDF2 + AES/3DES）与 pbeWithSHAAnd3-KeyTripleDES-CBC 加密，以及 SHA-1/SHA-2 MAC 校验。
+// 覆盖 OpenSSL 3 的默认输出和 -legacy 以外的常见导出格式，RC2 加密不支持
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKeyBag  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+
+	oidPBEWithSHAAnd3DES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBES2             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// ErrPKCS12Password PKCS#12 密码错误
+var ErrPKCS12Password = errors.New("PKCS#12 密码错误")
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	Id         asn1.ObjectIdentifier
+	Value      asn1.RawValue `asn1:"tag:0,explicit"`
+	Attributes asn1.RawValue `asn1:"optional"`
+}
+
+type certBag struct {
+	Id   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	Kdf              pkix.AlgorithmIdentifier
+	EncryptionScheme pkix.AlgorithmIdentifier
+}
+
+// 迭代次数的上限，超出时拒绝解析，避免恶意文件消耗大量 CPU
+const maxPKCS12Iterations = 1 << 20
+
+// checkIterations 检查迭代次数是否在合理范围内
+func checkIterations(n int) error {
+	if n < 1 || n > maxPKCS12Iterations {
+		return fmt.Errorf("PKCS#12 迭代次数 %d 超出范围", n)
+	}
+	return nil
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	Prf        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// LoadPKCS12 解析 PKCS#12（.p12/.pfx）数据，返回客户端证书。
+// 与私钥匹配的证书作为叶子证书，其余证书作为证书链
+func LoadPKCS12(data []byte, password string) (tls.Certificate, error) {
+	key, certs, err := decodePKCS12(data, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if key == nil {
+		return tls.Certificate{}, errors.New("PKCS#12 中没有私钥")
+	}
+	pub, ok := key.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return tls.Certificate{}, fmt.Errorf("不支持的私钥类型 %T", key)
+	}
+	leaf := -1
+	for i, cert := range certs {
+		if k, ok := pub.Public().(interface{ Equal(crypto.PublicKey) bool }); ok && k.Equal(cert.PublicKey) {
+			leaf = i
+			break
+		}
+	}
+	if leaf < 0 {
+		return tls.Certificate{}, errors.New("PKCS#12 中没有与私钥匹配的证书")
+	}
+	out := tls.Certificate{PrivateKey: key, Leaf: certs[leaf]}
+	out.Certificate = append(out.Certificate, certs[leaf].Raw)
+	for i, cert := range certs {
+		if i != leaf {
+			out.Certificate = append(out.Certificate, cert.Raw)
+		}
+	}
+	return out, nil
+}
+
+// decodePKCS12 解出私钥和全部证书
+func decodePKCS12(data []byte, password string) (crypto.PrivateKey, []*x509.Certificate, error) {
+	var pfx pfxPdu
+	if err := unmarshalDER(data, &pfx); err != nil {
+		return nil, nil, fmt.Errorf("解析 PKCS#12 失败: %w", err)
+	}
+	if pfx.Version != 3 {
+		return nil, nil, fmt.Errorf("不支持的 PKCS#12 版本 %d", pfx.Version)
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidData) {
+		return nil, nil, errors.New("不支持公钥加密的 PKCS#12")
+	}
+	var authSafe []byte
+	if err := unmarshalDER(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, nil, fmt.Errorf("解析 PKCS#12 失败: %w", err)
+	}
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		if err := verifyMac(&pfx.MacData, authSafe, password); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var contents []contentInfo
+	if err := unmarshalDER(authSafe, &contents); err != nil {
+		return nil, nil, fmt.Errorf("解析 PKCS#12 失败: %w", err)
+	}
+	var (
+		key   crypto.PrivateKey
+		certs []*x509.Certificate
+	)
+	for _, ci := range contents {
+		var plain []byte
+		switch {
+		case ci.ContentType.Equal(oidData):
+			if err := unmarshalDER(ci.Content.Bytes, &plain); err != nil {
+				return nil, nil, fmt.Errorf("解析 PKCS#12 失败: %w", err)
+			}
+		case ci.ContentType.Equal(oidEncryptedData):
+			var ed encryptedData
+			if err := unmarshalDER(ci.Content.Bytes, &ed); err != nil {
+				return nil, nil, fmt.Errorf("解析 PKCS#12 失败: %w", err)
+			}
+			var err error
+			info := ed.EncryptedContentInfo
+			if plain, err = pbeDecrypt(info.ContentEncryptionAlgorithm, info.EncryptedContent, password); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, fmt.Errorf("不支持的 PKCS#12 内容类型 %s", ci.ContentType)
+		}
+
+		var bags []safeBag
+		if err := unmarshalDER(plain, &bags); err != nil {
+			return nil, nil, fmt.Errorf("解析 PKCS#12 失败: %w", err)
+		}
+		for _, bag := range bags {
+			switch {
+			case bag.Id.Equal(oidCertBag):
+				var cb certBag
+				if err := unmarshalDER(bag.Value.Bytes, &cb); err != nil {
+					return nil, nil, fmt.Errorf("解析证书失败: %w", err)
+				}
+				if !cb.Id.Equal(oidX509Certificate) {
+					continue
+				}
+				cert, err := x509.ParseCertificate(cb.Data)
+				if err != nil {
+					return nil, nil, fmt.Errorf("解析证书失败: %w", err)
+				}
+				certs = append(certs, cert)
+			case bag.Id.Equal(oidKeyBag), bag.Id.Equal(oidShroudedKeyBag):
+				der := bag.Value.Bytes
+				if bag.Id.Equal(oidShroudedKeyBag) {
+					var epki encryptedPrivateKeyInfo
+					if err := unmarshalDER(der, &epki); err != nil {
+						return nil, nil, fmt.Errorf("解析私钥失败: %w", err)
+					}
+					var err error
+					if der, err = pbeDecrypt(epki.Algorithm, epki.EncryptedData, password); err != nil {
+						return nil, nil, err
+					}
+				}
+				k, err := x509.ParsePKCS8PrivateKey(der)
+				if err != nil {
+					return nil, nil, fmt.Errorf("解析私钥失败: %w", err)
+				}
+				key = k
+			}
+		}
+	}
+	return key, certs, nil
+}
+
+// unmarshalDER 解析 DER，不允许有多余数据
+func unmarshalDER(data []byte, v any) error {
+	rest, err := asn1.Unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("多余的数据")
+	}
+	return nil
+}
+
+// verifyMac 校验完整性，MAC 不匹配说明密码错误或文件损坏
+func verifyMac(md *macData, content []byte, password string) error {
+	h, err := digestHash(md.Mac.Algorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+	if err := checkIterations(md.Iterations); err != nil {
+		return err
+	}
+	key := pkcs12KDF(h, bmpString(password), md.MacSalt, md.Iterations, 3, h().Size())
+	mac := hmac.New(h, key)
+	mac.Write(content)
+	if !hmac.Equal(mac.Sum(nil), md.Mac.Digest) {
+		return ErrPKCS12Password
+	}
+	return nil
+}
+
+// pbeDecrypt 按算法标识解密
+func pbeDecrypt(alg pkix.AlgorithmIdentifier, data []byte, password string) ([]byte, error) {
+	var (
+		block cipher.Block
+		iv    []byte
+	)
+	switch {
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd3DES):
+		var params pbeParams
+		if err := unmarshalDER(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("解析加密参数失败: %w", err)
+		}
+		if err := checkIterations(params.Iterations); err != nil {
+			return nil, err
+		}
+		pass := bmpString(password)
+		key := pkcs12KDF(sha1.New, pass, params.Salt, params.Iterations, 1, 24)
+		iv = pkcs12KDF(sha1.New, pass, params.Salt, params.Iterations, 2, 8)
+		var err error
+		if block, err = des.NewTripleDESCipher(key); err != nil {
+			return nil, err
+		}
+	case alg.Algorithm.Equal(oidPBES2):
+		var params pbes2Params
+		if err := unmarshalDER(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("解析加密参数失败: %w", err)
+		}
+		var err error
+		if block, iv, err = pbes2Cipher(&params, password); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的 PKCS#12 加密算法 %s", alg.Algorithm)
+	}
+
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("PKCS#12 密文长度无效")
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+
+	// 去掉 PKCS#7 填充，填充无效通常是密码错误
+	n := int(out[len(out)-1])
+	if n == 0 || n > block.BlockSize() || !bytes.Equal(out[len(out)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, ErrPKCS12Password
+	}
+	return out[:len(out)-n], nil
+}
+
+// pbes2Cipher 由 PBES2 参数派生密钥
+func pbes2Cipher(params *pbes2Params, password string) (cipher.Block, []byte, error) {
+	if !params.Kdf.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("不支持的密钥派生算法 %s", params.Kdf.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if err := unmarshalDER(params.Kdf.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, fmt.Errorf("解析密钥派生参数失败: %w", err)
+	}
+	prf := sha1.New
+	switch {
+	case len(kdf.Prf.Algorithm) == 0, kdf.Prf.Algorithm.Equal(oidHMACWithSHA1):
+	case kdf.Prf.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case kdf.Prf.Algorithm.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, nil, fmt.Errorf("不支持的 PRF 算法 %s", kdf.Prf.Algorithm)
+	}
+
+	scheme := params.EncryptionScheme
+	var (
+		keyLen    int
+		newCipher func([]byte) (cipher.Block, error)
+	)
+	switch {
+	case scheme.Algorithm.Equal(oidAES128CBC):
+		keyLen, newCipher = 16, aes.NewCipher
+	case scheme.Algorithm.Equal(oidAES192CBC):
+		keyLen, newCipher = 24, aes.NewCipher
+	case scheme.Algorithm.Equal(oidAES256CBC):
+		keyLen, newCipher = 32, aes.NewCipher
+	case scheme.Algorithm.Equal(oidDESEDE3CBC):
+		keyLen, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, nil, fmt.Errorf("不支持的加密算法 %s", scheme.Algorithm)
+	}
+	if kdf.KeyLength != 0 && kdf.KeyLength != keyLen {
+		return nil, nil, fmt.Errorf("PKCS#12 密钥长度 %d 与加密算法不符", kdf.KeyLength)
+	}
+	if err := checkIterations(kdf.Iterations); err != nil {
+		return nil, nil, err
+	}
+	var iv []byte
+	if err := unmarshalDER(scheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, fmt.Errorf("解析加密参数失败: %w", err)
+	}
+
+	key, err := pbkdf2.Key(prf, password, kdf.Salt, kdf.Iterations, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, nil, errors.New("PKCS#12 初始向量长度无效")
+	}
+	return block, iv, nil
+}
+
+// digestHash MAC 使用的摘要算法
+func digestHash(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA1):
+		return sha1.New, nil
+	case oid.Equal(oidSHA256):
+		return sha256.New, nil
+	case oid.Equal(oidSHA512):
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("不支持的 MAC 算法 %s", oid)
+}
+
+// bmpString 把密码编码为以两个零字节结尾的 UTF-16BE
+func bmpString(s string) []byte {
+	var out []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(r>>8), byte(r))
+	}
+	return append(out, 0, 0)
+}
+
+// pkcs12KDF RFC 7292 附录 B.2 的密钥派生，id 为 1（密钥）、2（初始向量）、3（MAC 密钥）
+func pkcs12KDF(h func() hash.Hash, password, salt []byte, iterations int, id byte, size int) []byte {
+	u := h().Size()
+	v := h().BlockSize()
+
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	D := bytes.Repeat([]byte{id}, v)
+	I := append(fill(salt), fill(password)...)
+
+	var out []byte
+	for len(out) < size {
+		A := append(append([]byte{}, D...), I...)
+		for i := 0; i < iterations; i++ {
+			sum := h()
+			sum.Write(A)
+			A = sum.Sum(nil)
+		}
+		out = append(out, A[:min(u, size-len(out))]...)
+
+		// I 的每个 v 字节块加上 B + 1
+		B := make([]byte, v)
+		for i := range B {
+			B[i] = A[i%u]
+		}
+		for j := 0; j < len(I); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(I[j+k]) + int(B[k]) + carry
+				I[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package greqs
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPBES2Cipher(t *testing.T) {
+	iv, _ := asn1.Marshal(make([]byte, 16))
+	params := func(iterations, keyLength int) *pbes2Params {
+		kdf, err := asn1.Marshal(pbkdf2Params{Salt: []byte("salt"), Iterations: iterations, KeyLength: keyLength})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &pbes2Params{
+			Kdf:              pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdf}},
+			EncryptionScheme: pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: iv}},
+		}
+	}
+	tests := []struct {
+		name       string
+		iterations int
+		keyLength  int
+		err        string
+	}{
+		{"默认", 2048, 0, ""},
+		{"密钥长度一致", 2048, 32, ""},
+		{"密钥长度不一致", 2048, 16, "密钥长度"},
+		{"迭代次数过大", maxPKCS12Iterations + 1, 0, "迭代次数"},
+		{"迭代次数为 0", 0, 0, "迭代次数"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := pbes2Cipher(params(tt.iterations, tt.keyLength), "secret")
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("err = %v", err)
+			}
+		})
+	}
+}
+
+func FuzzLoadPKCS12(f *testing.F) {
+	for _, file := range []string{"testdata/client.p12", "testdata/client-3des.p12"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data, "secret")
+	}
+	f.Fuzz(func(t *testing.T, data []byte, password string) {
+		// 任意输入都不能 panic
+		LoadPKCS12(data, password)
+	})
+}
@@ -0,0 +1,161 @@
+package greqs
+
+import (
+	"context"
+	"net"
+	"net/http"
+	_url "net/url"
+	"os"
+	"strings"
+)
+
+// proxyKey 上下文中代理的键
+type proxyKey struct{}
+
+// WithProxy 为请求的上下文指定代理，优先于 Worker 和 Transport 的代理配置，proxy 为 nil 表示直连
+func WithProxy(ctx context.Context, proxy *_url.URL) context.Context {
+	return context.WithValue(ctx, proxyKey{}, proxy)
+}
+
+// ProxyFromContext 获取上下文中指定的代理，ok 为 false 表示没有指定
+func ProxyFromContext(ctx context.Context) (proxy *_url.URL, ok bool) {
+	proxy, ok = ctx.Value(proxyKey{}).(*_url.URL)
+	return proxy, ok
+}
+
+// ProxyConfig 代理解析配置，按 Func、Bypass、固定代理、环境变量的顺序决定每个请求使用的代理
+type ProxyConfig struct {
+	// FromEnv 遵循 HTTP_PROXY、HTTPS_PROXY、NO_PROXY 环境变量：
+	// 没有固定代理时使用环境变量中的代理，NO_PROXY 中的主机（包括有固定代理时）直连
+	FromEnv bool
+	// Bypass 直连的主机列表：example.com（包括子域名）、*.example.com 或 .example.com（仅子域名）、
+	// IP、CIDR（10.0.0.0/8）、带端口的 host:port，* 表示全部直连
+	Bypass []string
+	// Func 自定义每个请求的代理，返回 nil 表示直连，设置后忽略其他规则
+	Func func(req *http.Request) (*_url.URL, error)
+}
+
+// Resolve 解析请求使用的代理，fixed 为 Worker 或 Options 中配置的代理，返回 nil 表示直连
+func (c *ProxyConfig) Resolve(req *http.Request, fixed *_url.URL) (*_url.URL, error) {
+	if c.Func != nil {
+		return c.Func(req)
+	}
+
+	bypass := c.Bypass
+	if c.FromEnv {
+		// 复制后再追加，避免写入 c.Bypass 的底层数组（多个请求并发解析时会互相覆盖）
+		bypass = append(append([]string(nil), c.Bypass...), envNoProxy()...)
+	}
+	if MatchBypass(bypass, req.URL) {
+		return nil, nil
+	}
+	if fixed != nil {
+		return fixed, nil
+	}
+	if c.FromEnv {
+		return envProxy(req.URL)
+	}
+	return nil, nil
+}
+
+// getenv 读取环境变量，大写优先
+func getenv(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToLower(name))
+}
+
+// envProxy 读取 HTTP_PROXY、HTTPS_PROXY 环境变量（每次读取，不像 http.ProxyFromEnvironment 只读取一次）
+func envProxy(u *_url.URL) (*_url.URL, error) {
+	proxy := getenv("HTTP_PROXY")
+	if u.Scheme == "https" {
+		proxy = getenv("HTTPS_PROXY")
+	}
+	if proxy == "" {
+		return nil, nil
+	}
+	return ParseProxy(proxy)
+}
+
+// envNoProxy 读取 NO_PROXY 环境变量
+func envNoProxy() []string {
+	value := getenv("NO_PROXY")
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// MatchBypass 判断网址是否命中直连列表
+func MatchBypass(bypass []string, u *_url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range bypass {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+		entry = strings.Trim(entry, "[]")
+		if entryIP := net.ParseIP(entry); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(entry, "*."):
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+		case strings.HasPrefix(entry, "."):
+			if strings.HasSuffix(host, entry) {
+				return true
+			}
+		default:
+			if host == entry || strings.HasSuffix(host, "."+entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveProxy 按配置为一次尝试选择代理，结果写入请求的上下文
+func resolveProxy(cfg *ProxyConfig, fixed string, req *http.Request, send Handler) (*Response, error) {
+	var fixedURL *_url.URL
+	if fixed != "" {
+		var err error
+		if fixedURL, err = ParseProxy(fixed); err != nil {
+			return nil, err
+		}
+	}
+	proxy, err := cfg.Resolve(req, fixedURL)
+	if err != nil {
+		return nil, err
+	}
+	return send(req.WithContext(WithProxy(req.Context(), proxy)))
+}
@@ -0,0 +1,106 @@
+package greqs
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchBypass(t *testing.T) {
+	bypass := []string{"example.com", "*.internal", ".corp.net", "10.0.0.0/8", "192.168.1.1", "api.test:8443"}
+	cases := map[string]bool{
+		"http://example.com/":      true,
+		"http://a.example.com/":    true,
+		"http://notexample.com/":   false,
+		"http://svc.internal/":     true,
+		"http://internal/":         false,
+		"http://x.corp.net/":       true,
+		"http://corp.net/":         false,
+		"http://10.1.2.3/":         true,
+		"http://11.1.2.3/":         false,
+		"http://192.168.1.1:8080/": true,
+		"https://api.test:8443/":   true,
+		"https://api.test/":        false,
+		"http://[::1]/":            false,
+	}
+	for raw, want := range cases {
+		u, _ := url.Parse(raw)
+		if got := MatchBypass(bypass, u); got != want {
+			t.Errorf("%s: got %v, want %v", raw, got, want)
+		}
+	}
+	u, _ := url.Parse("http://anything/")
+	if !MatchBypass([]string{"*"}, u) {
+		t.Error("* should match everything")
+	}
+}
+
+func TestWorker_ProxyConfig(t *testing.T) {
+	proxy := newProxyServer("proxy")
+	defer proxy.Close()
+	direct := newEchoServer()
+	defer direct.Close()
+
+	// 固定代理 + 直连列表：127.0.0.1 直连，其他走代理
+	worker := NewWorker(proxy.URL, 5*time.Second, nil, nil)
+	worker.SetProxyConfig(&ProxyConfig{Bypass: []string{"127.0.0.0/8"}})
+	resp, err := worker.Put(direct.URL, nil, A{"direct": true})
+	if err != nil || resp.Text() != `{"direct":true}` {
+		t.Errorf("bypass: %v %s", err, resp.Text())
+	}
+	resp, err = worker.Get("http://example.com/", nil)
+	if err != nil || !strings.HasPrefix(resp.Text(), "proxy") {
+		t.Errorf("proxied: %v %s", err, resp.Text())
+	}
+
+	// 环境变量
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	t.Setenv("NO_PROXY", "skip.example.com")
+	worker = NewWorker("", 5*time.Second, nil, nil)
+	worker.SetProxyConfig(&ProxyConfig{FromEnv: true})
+	resp, err = worker.Get("http://example.com/", nil)
+	if err != nil || !strings.HasPrefix(resp.Text(), "proxy") {
+		t.Errorf("env: %v %s", err, resp.Text())
+	}
+	var proxied *url.URL
+	cfg := &ProxyConfig{FromEnv: true}
+	req, _ := http.NewRequest("GET", "http://skip.example.com/", nil)
+	if proxied, _ = cfg.Resolve(req, nil); proxied != nil {
+		t.Errorf("NO_PROXY host resolved to %s", proxied)
+	}
+}
+
+func TestOptions_ProxyConfigFunc(t *testing.T) {
+	proxy := newProxyServer("proxy")
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	opts := &Options{ProxyConfig: &ProxyConfig{Func: func(req *http.Request) (*url.URL, error) {
+		if strings.HasPrefix(req.URL.Path, "/via-proxy") {
+			return proxyURL, nil
+		}
+		return nil, nil
+	}}}
+	resp, err := SendGetRequest("http://example.com/via-proxy", opts)
+	if err != nil || resp.Text() != "proxy http://example.com/via-proxy" {
+		t.Errorf("func: %v %s", err, resp.Text())
+	}
+}
+
+func TestProxyConfig_BypassNotModified(t *testing.T) {
+	t.Setenv("NO_PROXY", "env.example.com")
+
+	// Bypass 留有容量时，合并环境变量不能写入它的底层数组
+	bypass := make([]string, 1, 4)
+	bypass[0] = "a.example.com"
+	cfg := &ProxyConfig{FromEnv: true, Bypass: bypass}
+	u, _ := url.Parse("http://b.example.com")
+	if _, err := cfg.Resolve(&http.Request{URL: u}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if full := bypass[:cap(bypass)]; full[1] != "" {
+		t.Errorf("Bypass 的底层数组被修改: %q", full)
+	}
+}
@@ -0,0 +1,286 @@
+package greqs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	_url "net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoProxy 代理池中没有可用的代理
+var ErrNoProxy = errors.New("没有可用的代理")
+
+// Strategy 代理选择策略
+type Strategy int
+
+const (
+	RoundRobin    Strategy = iota // 轮询
+	Random                        // 随机
+	LeastFailures                 // 累计失败次数最少
+	StickyPerHost                 // 同一主机固定使用同一代理，代理不可用时重新选择
+)
+
+// ProxyStat 代理的健康状态
+type ProxyStat struct {
+	Proxy     string    // 代理
+	Failures  int       // 连续失败次数
+	Total     int       // 累计失败次数
+	BadUntil  time.Time // 冷却结束时间，之前不会被选中
+	Available bool      // 当前是否可用
+}
+
+// ProxyPool 代理池，按策略为每个请求选择代理，连续失败达到阈值的代理会冷却一段时间
+type ProxyPool struct {
+	mu          sync.Mutex
+	proxies     []*ProxyStat
+	urls        map[string]*_url.URL
+	strategy    Strategy
+	maxFailures int
+	cooldown    time.Duration
+	next        int
+	sticky      map[string]*stickyProxy
+	stickyTTL   time.Duration
+	lastSweep   time.Time
+	provider    func() ([]string, error)
+}
+
+// DefaultStickyTimeout StickyPerHost 策略下主机多久没有请求后忘记它固定的代理
+const DefaultStickyTimeout = 10 * time.Minute
+
+// stickyProxy 主机固定使用的代理
+type stickyProxy struct {
+	proxy string
+	last  time.Time // 最后一次使用的时间
+}
+
+// NewProxyPool 创建代理池，默认连续失败 3 次后冷却 1 分钟
+func NewProxyPool(proxies []string, strategy Strategy) (*ProxyPool, error) {
+	p := &ProxyPool{
+		strategy:    strategy,
+		maxFailures: 3,
+		cooldown:    time.Minute,
+		sticky:      map[string]*stickyProxy{},
+		stickyTTL:   DefaultStickyTimeout,
+		lastSweep:   time.Now(),
+	}
+	if err := p.SetProxies(proxies); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadProxyPool 从文件加载代理池，每行一个代理，忽略空行和 # 开头的注释
+func LoadProxyPool(path string, strategy Strategy) (*ProxyPool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var proxies []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			proxies = append(proxies, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewProxyPool(proxies, strategy)
+}
+
+// NewProxyPoolFromProvider 使用提供函数创建代理池，之后可以调用 Refresh 重新获取
+func NewProxyPoolFromProvider(provider func() ([]string, error), strategy Strategy) (*ProxyPool, error) {
+	proxies, err := provider()
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewProxyPool(proxies, strategy)
+	if err != nil {
+		return nil, err
+	}
+	p.provider = provider
+	return p, nil
+}
+
+// Refresh 重新从提供函数获取代理，已有代理的健康状态会保留
+func (p *ProxyPool) Refresh() error {
+	if p.provider == nil {
+		return fmt.Errorf("代理池没有提供函数")
+	}
+	proxies, err := p.provider()
+	if err != nil {
+		return err
+	}
+	return p.SetProxies(proxies)
+}
+
+// SetProxies 替换代理列表，已有代理的健康状态会保留
+func (p *ProxyPool) SetProxies(proxies []string) error {
+	urls := make(map[string]*_url.URL, len(proxies))
+	for _, proxy := range proxies {
+		u, err := ParseProxy(proxy)
+		if err != nil {
+			return err
+		}
+		urls[proxy] = u
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old := make(map[string]*ProxyStat, len(p.proxies))
+	for _, st := range p.proxies {
+		old[st.Proxy] = st
+	}
+	p.proxies = p.proxies[:0:0]
+	for _, proxy := range proxies {
+		st, ok := old[proxy]
+		if !ok {
+			st = &ProxyStat{Proxy: proxy}
+		}
+		p.proxies = append(p.proxies, st)
+	}
+	p.urls = urls
+	for host, sp := range p.sticky {
+		if _, ok := urls[sp.proxy]; !ok {
+			delete(p.sticky, host)
+		}
+	}
+	return nil
+}
+
+// SetHealth 设置健康检查参数：连续失败 maxFailures 次后冷却 cooldown
+func (p *ProxyPool) SetHealth(maxFailures int, cooldown time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxFailures = maxFailures
+	p.cooldown = cooldown
+}
+
+// SetStickyTimeout 设置 StickyPerHost 策略下主机多久没有请求后忘记它固定的代理，默认 DefaultStickyTimeout
+func (p *ProxyPool) SetStickyTimeout(timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stickyTTL = timeout
+}
+
+// sweepSticky 清理长时间没有请求的主机，每个 stickyTTL 最多执行一次（调用方需持有锁）
+func (p *ProxyPool) sweepSticky(now time.Time) {
+	if p.stickyTTL <= 0 || now.Sub(p.lastSweep) < p.stickyTTL {
+		return
+	}
+	p.lastSweep = now
+	for host, sp := range p.sticky {
+		if now.Sub(sp.last) >= p.stickyTTL {
+			delete(p.sticky, host)
+		}
+	}
+}
+
+// Pick 为 host 选择一个代理
+func (p *ProxyPool) Pick(host string) (*_url.URL, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var available []*ProxyStat
+	for _, st := range p.proxies {
+		if !st.BadUntil.After(now) {
+			available = append(available, st)
+		}
+	}
+	if len(available) == 0 {
+		return nil, ErrNoProxy
+	}
+
+	var chosen *ProxyStat
+	switch p.strategy {
+	case Random:
+		chosen = available[rand.Intn(len(available))]
+	case LeastFailures:
+		chosen = available[0]
+		for _, st := range available[1:] {
+			if st.Total < chosen.Total {
+				chosen = st
+			}
+		}
+	case StickyPerHost:
+		p.sweepSticky(now)
+		if sp, ok := p.sticky[host]; ok {
+			for _, st := range available {
+				if st.Proxy == sp.proxy {
+					chosen = st
+				}
+			}
+		}
+		if chosen == nil {
+			chosen = available[p.next%len(available)]
+			p.next++
+		}
+		p.sticky[host] = &stickyProxy{proxy: chosen.Proxy, last: now}
+	default:
+		chosen = available[p.next%len(available)]
+		p.next++
+	}
+	return p.urls[chosen.Proxy], nil
+}
+
+// Report 报告代理的请求结果，ok 为 false 时计为一次失败
+func (p *ProxyPool) Report(proxy *_url.URL, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, st := range p.proxies {
+		if u := p.urls[st.Proxy]; u != proxy && u.String() != proxy.String() {
+			continue
+		}
+		if ok {
+			st.Failures = 0
+			return
+		}
+		st.Failures++
+		st.Total++
+		if p.maxFailures > 0 && st.Failures >= p.maxFailures {
+			st.BadUntil = time.Now().Add(p.cooldown)
+			st.Failures = 0
+		}
+		return
+	}
+}
+
+// Stats 所有代理的健康状态
+func (p *ProxyPool) Stats() []ProxyStat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	stats := make([]ProxyStat, 0, len(p.proxies))
+	for _, st := range p.proxies {
+		s := *st
+		s.Available = !st.BadUntil.After(now)
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// use 为一次尝试选择代理并报告结果，网络错误和 407 计为失败
+func (p *ProxyPool) use(req *http.Request, send Handler) (*Response, error) {
+	proxy, err := p.Pick(req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(req.WithContext(WithProxy(req.Context(), proxy)))
+	ok := err == nil && resp.StatusCode != http.StatusProxyAuthRequired
+	if err != nil && req.Context().Err() != nil {
+		return resp, err // 调用方取消，不计入代理失败
+	}
+	p.Report(proxy, ok)
+	return resp, err
+}
@@ -0,0 +1,133 @@
+package greqs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 本地 HTTP 代理，直接返回自己的名字
+func newProxyServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name + " " + r.URL.String()))
+	}))
+}
+
+// 已关闭的代理地址，连接会失败
+func deadProxy() string {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func TestWorker_ProxyPoolRoundRobin(t *testing.T) {
+	p1, p2 := newProxyServer("p1"), newProxyServer("p2")
+	defer p1.Close()
+	defer p2.Close()
+
+	pool, err := NewProxyPool([]string{p1.URL, p2.URL}, RoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetProxyPool(pool)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		resp, err := worker.Get("http://example.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, resp.Text()[:2])
+	}
+	if got[0] != "p1" || got[1] != "p2" || got[2] != "p1" || got[3] != "p2" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestWorker_ProxyPoolHealth(t *testing.T) {
+	good := newProxyServer("good")
+	defer good.Close()
+	bad := deadProxy()
+
+	pool, _ := NewProxyPool([]string{bad, good.URL}, StickyPerHost)
+	pool.SetHealth(1, time.Minute)
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetProxyPool(pool)
+	worker.SetRetry(&RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	// 第一次选中坏代理失败并冷却，重试时换到好代理，之后固定使用好代理
+	for i := 0; i < 3; i++ {
+		resp, err := worker.Get("http://example.com/", nil)
+		if err != nil || resp.Text()[:4] != "good" {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	stats := pool.Stats()
+	if stats[0].Available || stats[0].Total != 1 || !stats[1].Available {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	pool.SetProxies([]string{bad})
+	if _, err := worker.Get("http://example.com/", nil); !errors.Is(err, ErrNoProxy) {
+		t.Errorf("err = %v, want ErrNoProxy", err)
+	}
+}
+
+func TestLoadProxyPool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxies.txt")
+	os.WriteFile(path, []byte("# comment\nhttp://127.0.0.1:1\n\nhttp://127.0.0.1:2\n"), 0644)
+
+	pool, err := LoadProxyPool(path, LeastFailures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := pool.Pick("a.com")
+	pool.Report(first, false)
+	second, _ := pool.Pick("a.com")
+	if len(pool.Stats()) != 2 || first.String() == second.String() {
+		t.Errorf("first = %s, second = %s", first, second)
+	}
+
+	calls := 0
+	provided, err := NewProxyPoolFromProvider(func() ([]string, error) {
+		calls++
+		return []string{"http://127.0.0.1:3"}, nil
+	}, Random)
+	if err != nil || provided.Refresh() != nil || calls != 2 {
+		t.Errorf("provider calls = %d, err = %v", calls, err)
+	}
+}
+
+func TestProxyPool_StickyTimeout(t *testing.T) {
+	pool, err := NewProxyPool([]string{"127.0.0.1:1", "127.0.0.1:2"}, StickyPerHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SetStickyTimeout(20 * time.Millisecond)
+	a, _ := pool.Pick("a.com")
+	if b, _ := pool.Pick("a.com"); b != a {
+		t.Fatalf("同一主机应该固定使用 %s，得到 %s", a, b)
+	}
+
+	// a.com 长时间没有请求后被清理
+	time.Sleep(30 * time.Millisecond)
+	pool.Pick("b.com")
+	pool.mu.Lock()
+	_, ok := pool.sticky["a.com"]
+	n := len(pool.sticky)
+	pool.mu.Unlock()
+	if ok || n != 1 {
+		t.Errorf("sticky 还有 %d 个主机", n)
+	}
+
+	// 移除代理时同时清理固定到它的主机
+	pool.SetProxies([]string{"127.0.0.1:3"})
+	if len(pool.sticky) != 0 {
+		t.Errorf("sticky = %v", pool.sticky)
+	}
+}
@@ -0,0 +1,252 @@
+package greqs
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Rate 令牌桶速率，PerSecond 不大于 0 表示不限制
+type Rate struct {
+	PerSecond float64 // 每秒请求数
+	Burst     int     // 突发请求数，默认 1
+}
+
+// bucket 令牌桶
+type bucket struct {
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate Rate) *bucket {
+	if rate.Burst <= 0 {
+		rate.Burst = 1
+	}
+	return &bucket{rate: rate, tokens: float64(rate.Burst), last: time.Now()}
+}
+
+// reserve 预订一个令牌，返回需要等待的时间（调用方需持有锁）
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if b.rate.PerSecond <= 0 {
+		return 0
+	}
+	b.tokens = min(float64(b.rate.Burst), b.tokens+now.Sub(b.last).Seconds()*b.rate.PerSecond)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate.PerSecond * float64(time.Second))
+}
+
+// full 令牌桶在 now 时是否已经装满，装满的桶与新建的桶等价（调用方需持有锁）
+func (b *bucket) full(now time.Time) bool {
+	return b.rate.PerSecond <= 0 || b.tokens+now.Sub(b.last).Seconds()*b.rate.PerSecond >= float64(b.rate.Burst)
+}
+
+// cancel 归还预订的令牌（调用方需持有锁）
+func (b *bucket) cancel() {
+	if b.rate.PerSecond > 0 {
+		b.tokens++
+	}
+}
+
+// HostStats 单个主机的限流统计
+type HostStats struct {
+	Waiting   int           // 正在等待的请求数
+	Active    int           // 正在进行的请求数
+	Waits     int64         // 累计等待过的请求数
+	WaitTotal time.Duration // 累计等待时间
+}
+
+// DefaultHostIdleTimeout 主机空闲多久后清理它的限流状态
+const DefaultHostIdleTimeout = 5 * time.Minute
+
+// hostLimit 单个主机的令牌桶、并发信号量和统计
+type hostLimit struct {
+	bucket *bucket
+	sem    chan struct{}
+	stats  HostStats
+	last   time.Time // 最后一次使用的时间
+}
+
+// RateLimiter 客户端限流器：全局和每个主机的令牌桶，以及每个主机的并发上限。
+// 空闲超过 idleTimeout 的主机（没有等待和进行中的请求，令牌桶已装满）会被清理，统计也随之清除
+type RateLimiter struct {
+	mu            sync.Mutex
+	global        *bucket
+	hostRate      Rate
+	hostRates     map[string]Rate
+	maxConcurrent int
+	hosts         map[string]*hostLimit
+	idleTimeout   time.Duration
+	lastSweep     time.Time
+}
+
+// NewRateLimiter 创建限流器，global 为全局速率，perHost 为每个主机的默认速率，maxConcurrent 为每个主机的并发上限（0 表示不限制）
+func NewRateLimiter(global, perHost Rate, maxConcurrent int) *RateLimiter {
+	return &RateLimiter{
+		global:        newBucket(global),
+		hostRate:      perHost,
+		hostRates:     map[string]Rate{},
+		maxConcurrent: maxConcurrent,
+		hosts:         map[string]*hostLimit{},
+		idleTimeout:   DefaultHostIdleTimeout,
+		lastSweep:     time.Now(),
+	}
+}
+
+// SetHostRate 为指定主机设置速率，覆盖默认的每主机速率
+func (l *RateLimiter) SetHostRate(host string, rate Rate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hostRates[host] = rate
+	if h, ok := l.hosts[host]; ok {
+		h.bucket = newBucket(rate)
+	}
+}
+
+func (l *RateLimiter) GetIdleTimeout() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.idleTimeout
+}
+
+// SetIdleTimeout 设置主机空闲多久后清理它的限流状态，默认 DefaultHostIdleTimeout
+func (l *RateLimiter) SetIdleTimeout(timeout time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.idleTimeout = timeout
+}
+
+// hostState 获取主机的令牌桶、信号量和统计（调用方需持有锁）
+func (l *RateLimiter) hostState(host string) (*bucket, chan struct{}, *HostStats) {
+	now := time.Now()
+	l.sweep(now)
+	h, ok := l.hosts[host]
+	if !ok {
+		rate, ok := l.hostRates[host]
+		if !ok {
+			rate = l.hostRate
+		}
+		h = &hostLimit{bucket: newBucket(rate)}
+		if l.maxConcurrent > 0 {
+			h.sem = make(chan struct{}, l.maxConcurrent)
+		}
+		l.hosts[host] = h
+	}
+	h.last = now
+	return h.bucket, h.sem, &h.stats
+}
+
+// sweep 清理空闲的主机，每个 idleTimeout 最多执行一次（调用方需持有锁）
+func (l *RateLimiter) sweep(now time.Time) {
+	if l.idleTimeout <= 0 || now.Sub(l.lastSweep) < l.idleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for host, h := range l.hosts {
+		if h.stats.Waiting == 0 && h.stats.Active == 0 && now.Sub(h.last) >= l.idleTimeout && h.bucket.full(now) {
+			delete(l.hosts, host)
+		}
+	}
+}
+
+// Wait 等待向 host 发送请求的许可，成功时返回的 release 必须在请求结束后调用。
+// ctx 取消时立即返回错误。
+func (l *RateLimiter) Wait(ctx context.Context, host string) (release func(), err error) {
+	start := time.Now()
+	l.mu.Lock()
+	b, sem, st := l.hostState(host)
+	st.Waiting++
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		st.Waiting--
+		if waited := time.Since(start); waited > time.Millisecond {
+			st.Waits++
+			st.WaitTotal += waited
+		}
+		if err == nil {
+			st.Active++
+		}
+		l.mu.Unlock()
+	}()
+
+	// 并发上限
+	if sem != nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		l.mu.Lock()
+		st.Active--
+		l.mu.Unlock()
+		if sem != nil {
+			<-sem
+		}
+	}
+
+	// 令牌桶，取全局和主机两者中较长的等待时间
+	l.mu.Lock()
+	now := time.Now()
+	delay := max(l.global.reserve(now), b.reserve(now))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return release, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.global.cancel()
+		b.cancel()
+		l.mu.Unlock()
+		if sem != nil {
+			<-sem
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Stats 各主机的限流统计，已清理的空闲主机不包含在内
+func (l *RateLimiter) Stats() map[string]HostStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := make(map[string]HostStats, len(l.hosts))
+	for host, h := range l.hosts {
+		stats[host] = h.stats
+	}
+	return stats
+}
+
+// limit 在限流许可内发送请求，流式响应在关闭时才释放许可
+func (l *RateLimiter) limit(req *http.Request, send Handler) (*Response, error) {
+	release, err := l.Wait(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(req)
+	if err != nil || !resp.IsStream() {
+		release()
+		return resp, err
+	}
+	cancel := resp.cancel
+	resp.cancel = func() {
+		if cancel != nil {
+			cancel()
+		}
+		release()
+	}
+	return resp, nil
+}
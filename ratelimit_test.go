@@ -0,0 +1,103 @@
+package greqs
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_PerHost(t *testing.T) {
+	srv, _ := newBatchServer()
+	defer srv.Close()
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	limiter := NewRateLimiter(Rate{}, Rate{PerSecond: 20, Burst: 2}, 0)
+	limiter.SetHostRate("other.com", Rate{PerSecond: 1000})
+	worker.SetRateLimiter(limiter)
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := worker.Get(srv.URL, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 突发 2 个，之后每 50ms 一个
+	if cost := time.Since(start); cost < 180*time.Millisecond {
+		t.Errorf("6 requests took %v, expected throttling", cost)
+	}
+	u, _ := url.Parse(srv.URL)
+	if st := limiter.Stats()[u.Host]; st.Waits == 0 || st.Active != 0 || st.Waiting != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestRateLimiter_Concurrency(t *testing.T) {
+	srv, peak := newBatchServer()
+	defer srv.Close()
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetRateLimiter(NewRateLimiter(Rate{}, Rate{}, 2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.Get(srv.URL, nil)
+		}()
+	}
+	wg.Wait()
+	if *peak > 2 {
+		t.Errorf("peak concurrency = %d, want <= 2", *peak)
+	}
+}
+
+func TestRateLimiter_Cancel(t *testing.T) {
+	limiter := NewRateLimiter(Rate{PerSecond: 1, Burst: 1}, Rate{}, 0)
+	release, err := limiter.Wait(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Wait(ctx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want DeadlineExceeded", err)
+	}
+
+	if st := limiter.Stats()["example.com"]; st.Waits != 1 || st.Waiting != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestRateLimiter_IdleEviction(t *testing.T) {
+	limiter := NewRateLimiter(Rate{}, Rate{PerSecond: 1000}, 1)
+	limiter.SetIdleTimeout(20 * time.Millisecond)
+	for _, host := range []string{"a.com", "b.com"} {
+		release, err := limiter.Wait(context.Background(), host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if host == "a.com" {
+			release()
+		} else {
+			defer release()
+		}
+	}
+
+	// a.com 空闲后被清理，b.com 还有进行中的请求，保留
+	time.Sleep(30 * time.Millisecond)
+	release, err := limiter.Wait(context.Background(), "c.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	stats := limiter.Stats()
+	if _, ok := stats["a.com"]; ok || len(stats) != 2 || stats["b.com"].Active != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
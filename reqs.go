@@ -1,33 +1,127 @@
 package greqs
 
+import "context"
+
 // Get 发送 GET 请求
 func Get(url string, headers S) (*Response, error) {
+	return GetCtx(context.Background(), url, headers)
+}
+
+// GetCtx 发送 GET 请求（携带上下文）
+func GetCtx(ctx context.Context, url string, headers S) (*Response, error) {
 	req := &Request{
 		Method:  "GET",
 		Url:     url,
 		Headers: headers,
 	}
-	return req.Do()
+	return req.DoContext(ctx)
 }
 
 // Post 发送 POST 请求（JOSN 形式）
 func Post(url string, headers S, data A) (*Response, error) {
+	return PostCtx(context.Background(), url, headers, data)
+}
+
+// PostCtx 发送 POST 请求（JSON 形式，携带上下文）
+func PostCtx(ctx context.Context, url string, headers S, data A) (*Response, error) {
 	req := &Request{
 		Method:  "POST",
 		Url:     url,
 		Headers: headers,
 		Data:    data,
 	}
-	return req.Do()
+	return req.DoContext(ctx)
 }
 
 // PostForm 发送 POST 请求（Form 形式）
 func PostForm(url string, headers S, form S) (*Response, error) {
+	return PostFormCtx(context.Background(), url, headers, form)
+}
+
+// PostFormCtx 发送 POST 请求（Form 形式，携带上下文）
+func PostFormCtx(ctx context.Context, url string, headers S, form S) (*Response, error) {
 	req := &Request{
 		Method:  "POST",
 		Url:     url,
 		Headers: headers,
 		Form:    form,
 	}
-	return req.Do()
+	return req.DoContext(ctx)
+}
+
+// Put 发送 PUT 请求（JSON 形式）
+func Put(url string, headers S, data A) (*Response, error) {
+	return PutCtx(context.Background(), url, headers, data)
+}
+
+// PutCtx 发送 PUT 请求（JSON 形式，携带上下文）
+func PutCtx(ctx context.Context, url string, headers S, data A) (*Response, error) {
+	req := &Request{
+		Method:  "PUT",
+		Url:     url,
+		Headers: headers,
+		Data:    data,
+	}
+	return req.DoContext(ctx)
+}
+
+// Patch 发送 PATCH 请求（JSON 形式）
+func Patch(url string, headers S, data A) (*Response, error) {
+	return PatchCtx(context.Background(), url, headers, data)
+}
+
+// PatchCtx 发送 PATCH 请求（JSON 形式，携带上下文）
+func PatchCtx(ctx context.Context, url string, headers S, data A) (*Response, error) {
+	req := &Request{
+		Method:  "PATCH",
+		Url:     url,
+		Headers: headers,
+		Data:    data,
+	}
+	return req.DoContext(ctx)
+}
+
+// Delete 发送 DELETE 请求
+func Delete(url string, headers S) (*Response, error) {
+	return DeleteCtx(context.Background(), url, headers)
+}
+
+// DeleteCtx 发送 DELETE 请求（携带上下文）
+func DeleteCtx(ctx context.Context, url string, headers S) (*Response, error) {
+	req := &Request{
+		Method:  "DELETE",
+		Url:     url,
+		Headers: headers,
+	}
+	return req.DoContext(ctx)
+}
+
+// Head 发送 HEAD 请求
+func Head(url string, headers S) (*Response, error) {
+	return HeadCtx(context.Background(), url, headers)
+}
+
+// HeadCtx 发送 HEAD 请求（携带上下文）
+func HeadCtx(ctx context.Context, url string, headers S) (*Response, error) {
+	req := &Request{
+		Method:  "HEAD",
+		Url:     url,
+		Headers: headers,
+	}
+	return req.DoContext(ctx)
+}
+
+// OptionsRequest 发送 OPTIONS 请求（Options 已用作请求配置的类型名）
+func OptionsRequest(url string, headers S) (*Response, error) {
+	return OptionsRequestCtx(context.Background(), url, headers)
+}
+
+// OptionsRequestCtx 发送 OPTIONS 请求（携带上下文）
+func OptionsRequestCtx(ctx context.Context, url string, headers S) (*Response, error) {
+	req := &Request{
+		Method:  "OPTIONS",
+		Url:     url,
+		Headers: headers,
+	}
+	return req.DoContext(ctx)
 }
@@ -2,6 +2,9 @@ package greqs
 
 import (
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -23,3 +26,61 @@ func TestReqs(t *testing.T) {
 	r3, _ := PostForm(url, nil, form)
 	fmt.Println(r3.Text())
 }
+
+// 本地回显服务，返回请求方法、请求头和请求体
+func newEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		w.Write(body)
+	}))
+}
+
+func TestMethods(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	data := A{"name": "Greqs"}
+	cases := []struct {
+		method string
+		call   func() (*Response, error)
+		body   string
+	}{
+		{"PUT", func() (*Response, error) { return Put(srv.URL, nil, data) }, `{"name":"Greqs"}`},
+		{"PATCH", func() (*Response, error) { return Patch(srv.URL, nil, data) }, `{"name":"Greqs"}`},
+		{"DELETE", func() (*Response, error) { return Delete(srv.URL, nil) }, ""},
+		{"HEAD", func() (*Response, error) { return Head(srv.URL, nil) }, ""},
+		{"OPTIONS", func() (*Response, error) { return OptionsRequest(srv.URL, nil) }, ""},
+	}
+	for _, c := range cases {
+		resp, err := c.call()
+		if err != nil {
+			t.Fatalf("%s err: %v", c.method, err)
+		}
+		if got := resp.Header.Get("X-Method"); got != c.method {
+			t.Errorf("method = %s, want %s", got, c.method)
+		}
+		if resp.Text() != c.body {
+			t.Errorf("%s body = %q, want %q", c.method, resp.Text(), c.body)
+		}
+	}
+}
+
+func TestSendMethod(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	resp, err := Send("delete", srv.URL, &Options{Params: S{"id": "1"}, Form: S{"force": "true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("X-Method") != "DELETE" || resp.Header.Get("X-Query") != "id=1" || resp.Text() != "force=true" {
+		t.Errorf("unexpected response: %v %s", resp.Header, resp.Text())
+	}
+
+	if _, err := Send("FETCH", srv.URL, nil); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
@@ -1,59 +1,80 @@
 package greqs
 
 import (
-	"fmt"
-	"strings"
+	"context"
+	"io"
+	"net/http"
 	"time"
 )
 
 // Request 请求
 type Request struct {
-	Method  string        // 请求方法 GET or POST
+	Method  string        // 请求方法 GET、POST、PUT、PATCH、DELETE、HEAD、OPTIONS 等
 	Url     string        // 网址
 	Params  S             // 查询字符串
 	Headers S             // 请求头
 	Data    A             // JSON 请求体
 	Form    S             // 请求表单
 	Proxy   string        // 代理
-	Timeout time.Duration // 超时
+	Timeout time.Duration // 超时（单次尝试）
+	Retry   *RetryPolicy  // 重试策略，为空时不重试
+
+	MaxBodySize int64             // 响应体最大长度，0 表示不限制
+	ProxyConfig *ProxyConfig      // 代理解析配置（环境变量、直连列表、自定义函数）
+	TLS         *TLSOptions       // TLS 配置，同一个配置共用一个客户端
+	Auth        Authenticator     // 认证器
+	Middlewares []Middleware      // 中间件（缓存、录制回放等）
+	Transport   http.RoundTripper // 自定义 Transport（例如测试用的 greqstest.Mock）
+
+	JSON        any        // 任意 JSON 请求体（结构体、切片等）
+	XML         any        // XML 请求体
+	Body        []byte     // 原始请求体
+	Reader      io.Reader  // 流式请求体
+	Multipart   *Multipart // 多部分表单（文件上传）
+	ContentType string     // 请求体类型，为空时根据请求体推断
 }
 
-// Do 执行请求
-func (r *Request) Do() (*Response, error) {
-	method := strings.ToUpper(r.Method)
-	if method != "GET" && method != "POST" {
-		return nil, fmt.Errorf("仅支持 GET、POST，不支持 %s", method)
+// Options 转换为请求配置
+func (r *Request) Options() *Options {
+	return &Options{
+		Params:      r.Params,
+		Headers:     r.Headers,
+		Data:        r.Data,
+		Form:        r.Form,
+		JSON:        r.JSON,
+		XML:         r.XML,
+		Body:        r.Body,
+		Reader:      r.Reader,
+		Multipart:   r.Multipart,
+		ContentType: r.ContentType,
+		Proxy:       r.Proxy,
+		Timeout:     r.Timeout,
+		Retry:       r.Retry,
+		MaxBodySize: r.MaxBodySize,
+		ProxyConfig: r.ProxyConfig,
+		TLS:         r.TLS,
+		Auth:        r.Auth,
+		Middlewares: r.Middlewares,
+		Transport:   r.Transport,
 	}
+}
 
-	if r.Params != nil {
-		r.Url = MakeUrl(r.Url, r.Params)
+// HTTPRequest 转换为 *http.Request
+func (r *Request) HTTPRequest() (*http.Request, error) {
+	opts := r.Options()
+	url := r.Url
+	if opts.Params != nil {
+		url = MakeUrl(url, opts.Params)
 	}
+	return MakeBodyRequest(r.Method, url, opts.Headers, opts.body())
+}
 
-	cli := GetClient(r.Proxy, r.Timeout)
-
-	if method == "GET" {
-		req, err := MakeGetRequest(r.Url, r.Headers)
-		if err != nil {
-			return nil, err
-		}
-		return Do(cli, req)
-
-	} else {
-		if r.Data != nil {
-			req, err := MakePostRequest(r.Url, r.Headers, r.Data)
-			if err != nil {
-				return nil, err
-			}
-			return Do(cli, req)
-
-		} else if r.Form != nil {
-			req, err := MakePostFormRequest(r.Url, r.Headers, r.Form)
-			if err != nil {
-				return nil, err
-			}
-			return Do(cli, req)
-		} else {
-			return nil, fmt.Errorf("无效的 POST 请求")
-		}
-	}
+// Do 执行请求
+func (r *Request) Do() (*Response, error) {
+	return r.DoContext(context.Background())
+}
+
+// DoContext 执行请求（携带上下文，上下文取消或超时后请求会被中断）
+func (r *Request) DoContext(ctx context.Context) (*Response, error) {
+	return SendContext(ctx, r.Method, r.Url, r.Options())
 }
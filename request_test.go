@@ -1,7 +1,11 @@
 package greqs
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -19,3 +23,37 @@ func TestTask_Do(t *testing.T) {
 	}
 	fmt.Println(resp.Text())
 }
+
+// 本地慢速服务，delay 后才响应
+func newSlowServer(delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.Write([]byte("done"))
+		case <-r.Context().Done():
+		}
+	}))
+}
+
+func TestRequest_DoContext(t *testing.T) {
+	srv := newSlowServer(time.Second)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	req := &Request{Method: "GET", Url: srv.URL}
+	if _, err := req.DoContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+
+	req.Timeout = 50 * time.Millisecond
+	if _, err := req.Do(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if cli := GetClient("", 0); cli.Timeout != 0 {
+		t.Errorf("shared client timeout modified: %v", cli.Timeout)
+	}
+}
@@ -2,12 +2,17 @@ package requests
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -16,28 +21,69 @@ import (
 type Options struct {
 	Params   map[string]string // URL查询参数
 	Headers  map[string]string // 请求头
-	JSON     []byte            // JSON请求体
+	JSON     []byte            // JSON请求体（已编码）
+	Data     any               // 任意JSON值（结构体、切片等，发送时编码）
+	XML      any               // XML请求体（发送时编码）
+	Body     []byte            // 原始请求体
+	Reader   io.Reader         // 流式请求体
 	FormData map[string]string // 表单数据
+	Files    map[string]string // 上传的文件（字段名 -> 文件路径），与 FormData 一起以 multipart 形式流式发送
 	Timeout  time.Duration     // 请求超时时间
 	Proxy    string            // 代理URL
+
+	ContentType string // 请求体类型，为空时根据请求体推断
+	MaxBodySize int64  // 响应体最大长度，超过时返回 *BodyTooLargeError，0 表示不限制
 }
 
 // 响应对象
 type Response struct {
 	*http.Response
 	Body []byte
+
+	stream bool               // 流式响应，响应体未读取
+	cancel context.CancelFunc // 流式响应关闭时释放上下文
+}
+
+// 响应体超过了允许的最大长度
+type BodyTooLargeError struct {
+	Limit int64 // 允许的最大长度
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("响应体超过最大长度 %d 字节", e.Limit)
+}
+
+// 响应体的读取器，流式响应返回未读取的响应体，否则返回 Body 的读取器
+func (r *Response) Reader() io.Reader {
+	if r.stream {
+		return r.Response.Body
+	}
+	return bytes.NewReader(r.Body)
+}
+
+// 关闭流式响应，非流式响应无需关闭
+func (r *Response) Close() error {
+	if !r.stream {
+		return nil
+	}
+	err := r.Response.Body.Close()
+	r.cancel()
+	return err
 }
 
 // 工作者
 type Worker struct {
 	client *http.Client
+	proxy  *url.URL // 默认代理，为空时使用环境变量中的代理
 }
 
 // new 一个工作者
 func NewWorker() *Worker {
-	return &Worker{
+	w := &Worker{
 		client: &http.Client{},
 	}
+	w.transport()
+	return w
 }
 
 // 设置默认超时
@@ -45,13 +91,70 @@ func (w *Worker) SetDefaultTimeout(timeout time.Duration) {
 	w.client.Timeout = timeout
 }
 
-// 设置默认代理
-func (w *Worker) SetDefaultProxy(proxy string) {
+// 设置默认代理，支持 http、https、socks5、socks5h 协议，用户名和密码写在地址中
+func (w *Worker) SetDefaultProxy(proxy string) error {
+	proxyURL, err := parseProxy(proxy)
+	if err != nil {
+		return err
+	}
+	w.proxy = proxyURL
+	w.transport()
+	return nil
+}
+
+// 设置 TLS 配置（根证书、客户端证书、最低版本、SNI 等），可以用 greqs.TLSOptions 的 Config 方法生成
+func (w *Worker) SetTLSConfig(cfg *tls.Config) {
+	w.transport().TLSClientConfig = cfg
+}
+
+// 获取客户端的 Transport，还没有时基于默认 Transport 创建，代理与 TLS 配置共用同一个 Transport
+func (w *Worker) transport() *http.Transport {
+	if t, ok := w.client.Transport.(*http.Transport); ok {
+		return t
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = w.proxyFor
+	w.client.Transport = t
+	return t
+}
+
+// 请求上下文中保存单次请求代理的键
+type proxyKey struct{}
+
+// 选择请求使用的代理，单次请求的代理（Options.Proxy）通过上下文传递，优先于默认代理，不会修改共用的 Transport
+func (w *Worker) proxyFor(req *http.Request) (*url.URL, error) {
+	if u, ok := req.Context().Value(proxyKey{}).(*url.URL); ok {
+		return u, nil
+	}
+	if w.proxy != nil {
+		return w.proxy, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// 解析代理地址，省略协议时视为 http
+func parseProxy(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
 	proxyURL, err := url.Parse(proxy)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("解析代理URL失败: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("不支持的代理协议: %s", proxyURL.Scheme)
 	}
-	w.client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("代理URL缺少主机: %s", proxy)
+	}
+	return proxyURL, nil
+}
+
+// 设置 Cookie 容器，用于在多次请求之间保持 Cookie
+func (w *Worker) SetCookieJar(jar http.CookieJar) {
+	w.client.Jar = jar
 }
 
 // 发送 GET 请求
@@ -59,11 +162,84 @@ func (c *Worker) Get(urlStr string, opts *Options) (*Response, error) {
 	return c.Send("GET", urlStr, opts)
 }
 
+// 发送 GET 请求（携带上下文）
+func (c *Worker) GetCtx(ctx context.Context, urlStr string, opts *Options) (*Response, error) {
+	return c.SendContext(ctx, "GET", urlStr, opts)
+}
+
 // 发送 POST 请求
 func (c *Worker) Post(urlStr string, opts *Options) (*Response, error) {
 	return c.Send("POST", urlStr, opts)
 }
 
+// 发送 POST 请求（携带上下文）
+func (c *Worker) PostCtx(ctx context.Context, urlStr string, opts *Options) (*Response, error) {
+	return c.SendContext(ctx, "POST", urlStr, opts)
+}
+
+// 发送 PUT 请求
+func (c *Worker) Put(urlStr string, opts *Options) (*Response, error) {
+	return c.Send("PUT", urlStr, opts)
+}
+
+// 发送 PUT 请求（携带上下文）
+func (c *Worker) PutCtx(ctx context.Context, urlStr string, opts *Options) (*Response, error) {
+	return c.SendContext(ctx, "PUT", urlStr, opts)
+}
+
+// 发送 PATCH 请求
+func (c *Worker) Patch(urlStr string, opts *Options) (*Response, error) {
+	return c.Send("PATCH", urlStr, opts)
+}
+
+// 发送 PATCH 请求（携带上下文）
+func (c *Worker) PatchCtx(ctx context.Context, urlStr string, opts *Options) (*Response, error) {
+	return c.SendContext(ctx, "PATCH", urlStr, opts)
+}
+
+// 发送 DELETE 请求
+func (c *Worker) Delete(urlStr string, opts *Options) (*Response, error) {
+	return c.Send("DELETE", urlStr, opts)
+}
+
+// 发送 DELETE 请求（携带上下文）
+func (c *Worker) DeleteCtx(ctx context.Context, urlStr string, opts *Options) (*Response, error) {
+	return c.SendContext(ctx, "DELETE", urlStr, opts)
+}
+
+// 发送 HEAD 请求
+func (c *Worker) Head(urlStr string, opts *Options) (*Response, error) {
+	return c.Send("HEAD", urlStr, opts)
+}
+
+// 发送 HEAD 请求（携带上下文）
+func (c *Worker) HeadCtx(ctx context.Context, urlStr string, opts *Options) (*Response, error) {
+	return c.SendContext(ctx, "HEAD", urlStr, opts)
+}
+
+// 发送 OPTIONS 请求
+func (c *Worker) Options(urlStr string, opts *Options) (*Response, error) {
+	return c.Send("OPTIONS", urlStr, opts)
+}
+
+// 发送 OPTIONS 请求（携带上下文）
+func (c *Worker) OptionsCtx(ctx context.Context, urlStr string, opts *Options) (*Response, error) {
+	return c.SendContext(ctx, "OPTIONS", urlStr, opts)
+}
+
+// 支持的 HTTP 方法
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
 // 构造完整的 url 地址
 func MakeUrl(urlStr string, params map[string]string) string {
 	q := url.Values{}
@@ -78,10 +254,104 @@ func MakeUrl(urlStr string, params map[string]string) string {
 	return urlStr
 }
 
-// 发送 HTTP 请求，根据方法类型 ( GET / POST ) 分别处理
+// 根据 opts 构造请求体，按 Reader、Body、Files、FormData、JSON、Data、XML 的顺序取第一个不为空的字段
+func makeBody(opts *Options) (io.Reader, string, error) {
+	if opts == nil {
+		return nil, "", nil
+	}
+
+	var (
+		reqBody     io.Reader
+		contentType string
+	)
+	switch {
+	case opts.Reader != nil:
+		reqBody, contentType = opts.Reader, "application/octet-stream"
+	case opts.Body != nil:
+		reqBody, contentType = bytes.NewReader(opts.Body), "application/octet-stream"
+	case opts.Files != nil:
+		// 文件上传，通过管道边读边写
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		go func() {
+			pw.CloseWithError(writeMultipart(writer, opts.FormData, opts.Files))
+		}()
+		reqBody, contentType = pr, writer.FormDataContentType()
+	case opts.FormData != nil:
+		// 表单数据
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for key, val := range opts.FormData {
+			_ = writer.WriteField(key, val)
+		}
+		writer.Close()
+		reqBody, contentType = body, writer.FormDataContentType()
+	case opts.JSON != nil:
+		// JSON请求体
+		reqBody, contentType = bytes.NewReader(opts.JSON), "application/json"
+	case opts.Data != nil:
+		b, err := json.Marshal(opts.Data)
+		if err != nil {
+			return nil, "", fmt.Errorf("编码JSON失败: %w", err)
+		}
+		reqBody, contentType = bytes.NewReader(b), "application/json"
+	case opts.XML != nil:
+		b, err := xml.Marshal(opts.XML)
+		if err != nil {
+			return nil, "", fmt.Errorf("编码XML失败: %w", err)
+		}
+		reqBody, contentType = bytes.NewReader(append([]byte(xml.Header), b...)), "application/xml"
+	}
+
+	if reqBody != nil && opts.ContentType != "" {
+		contentType = opts.ContentType
+	}
+	return reqBody, contentType, nil
+}
+
+// 写入表单字段和文件
+func writeMultipart(writer *multipart.Writer, fields, files map[string]string) error {
+	for key, val := range fields {
+		if err := writer.WriteField(key, val); err != nil {
+			return err
+		}
+	}
+	for field, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("打开文件失败: %w", err)
+		}
+		part, err := writer.CreateFormFile(field, filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// 发送 HTTP 请求，请求体由 opts 中的 Reader、Body、FormData、JSON、Data、XML 决定
 func (c *Worker) Send(method, urlStr string, opts *Options) (*Response, error) {
+	return c.SendContext(context.Background(), method, urlStr, opts)
+}
+
+// 发送 HTTP 请求（携带上下文，opts.Timeout 通过上下文实现，不会修改客户端的默认超时）
+func (c *Worker) SendContext(ctx context.Context, method, urlStr string, opts *Options) (*Response, error) {
+	return c.send(ctx, method, urlStr, opts, false)
+}
+
+// 以流式模式发送 HTTP 请求，响应体不会被读入内存，需要通过 Response.Reader 读取，用完后必须调用 Response.Close
+func (c *Worker) SendStream(ctx context.Context, method, urlStr string, opts *Options) (*Response, error) {
+	return c.send(ctx, method, urlStr, opts, true)
+}
+
+// 发送 HTTP 请求，stream 为 true 时不读取响应体
+func (c *Worker) send(ctx context.Context, method, urlStr string, opts *Options, stream bool) (*Response, error) {
 	method = strings.ToUpper(method)
-	if method != "GET" && method != "POST" {
+	if !methods[method] {
 		return nil, fmt.Errorf("不支持的HTTP方法: %s", method)
 	}
 
@@ -90,29 +360,35 @@ func (c *Worker) Send(method, urlStr string, opts *Options) (*Response, error) {
 		urlStr = MakeUrl(urlStr, opts.Params)
 	}
 
-	// POST
-	var reqBody io.Reader
-	contentType := ""
-	if opts != nil {
-		if opts.FormData != nil {
-			// 表单数据
-			body := &bytes.Buffer{}
-			writer := multipart.NewWriter(body)
-			for key, val := range opts.FormData {
-				_ = writer.WriteField(key, val)
-			}
-			writer.Close()
-			reqBody = body
-			contentType = writer.FormDataContentType()
-		} else if opts.JSON != nil {
-			// JSON请求体
-			reqBody = bytes.NewBuffer(opts.JSON)
-			contentType = "application/json"
+	// 请求体
+	reqBody, contentType, err := makeBody(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 单次请求的代理通过上下文传递
+	if opts != nil && opts.Proxy != "" {
+		proxyURL, err := parseProxy(opts.Proxy)
+		if err != nil {
+			return nil, err
 		}
+		ctx = context.WithValue(ctx, proxyKey{}, proxyURL)
 	}
 
+	// 设置请求超时，流式模式下在 Response.Close 时才释放，其余情况（包括出错）在返回时释放
+	cancel := context.CancelFunc(func() {})
+	if opts != nil && opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			cancel()
+		}
+	}()
+
 	// 创建 HTTP 请求
-	req, err := http.NewRequest(method, urlStr, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
@@ -122,30 +398,10 @@ func (c *Worker) Send(method, urlStr string, opts *Options) (*Response, error) {
 		req.Header.Set("Content-Type", contentType)
 	}
 
-	// 处理通用选项
-	if opts != nil {
-		// 设置自定义请求头
-		if opts.Headers != nil {
-			for key, val := range opts.Headers {
-				req.Header.Set(key, val)
-			}
-		}
-
-		// 设置请求超时
-		if opts.Timeout > 0 {
-			//ctx, cancel := context.WithTimeout(req.Context(), opts.Timeout)
-			//defer cancel()
-			//req = req.WithContext(ctx)
-			c.client.Timeout = opts.Timeout
-		}
-
-		// 设置代理
-		if opts.Proxy != "" {
-			proxyURL, err := url.Parse(opts.Proxy)
-			if err != nil {
-				return nil, fmt.Errorf("解析代理URL失败: %w", err)
-			}
-			c.client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+	// 设置自定义请求头
+	if opts != nil && opts.Headers != nil {
+		for key, val := range opts.Headers {
+			req.Header.Set(key, val)
 		}
 	}
 
@@ -154,15 +410,45 @@ func (c *Worker) Send(method, urlStr string, opts *Options) (*Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
+	if stream {
+		keep = true
+		return &Response{Response: res, stream: true, cancel: cancel}, nil
+	}
 	defer res.Body.Close()
 
 	// 读取响应体
-	bodyBytes, err := io.ReadAll(res.Body)
+	var limit int64
+	if opts != nil {
+		limit = opts.MaxBodySize
+	}
+	bodyBytes, err := readBody(res, limit)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应体失败: %w", err)
+		return nil, err
 	}
 
-	return &Response{res, bodyBytes}, nil
+	return &Response{Response: res, Body: bodyBytes}, nil
+}
+
+// 读取响应体，limit 大于 0 时超过 limit 字节返回 *BodyTooLargeError
+func readBody(res *http.Response, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		bodyBytes, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("读取响应体失败: %w", err)
+		}
+		return bodyBytes, nil
+	}
+	if res.ContentLength > limit {
+		return nil, &BodyTooLargeError{Limit: limit}
+	}
+	bodyBytes, err := io.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+	if int64(len(bodyBytes)) > limit {
+		return nil, &BodyTooLargeError{Limit: limit}
+	}
+	return bodyBytes, nil
 }
 
 // 响应的文本数据
@@ -180,6 +466,11 @@ func (r *Response) JSON() (map[string]any, error) {
 	return jsonMap, err
 }
 
+// 把响应的 JSON 数据解码到 v，支持结构体、切片、标量等任意类型
+func (r *Response) Decode(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
 // 响应的 JSON 字符串
 func (r *Response) JSONString() (string, error) {
 	jsonMap, err := r.JSON()
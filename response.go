@@ -2,8 +2,10 @@ package greqs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 )
 
@@ -11,6 +13,53 @@ import (
 type Response struct {
 	*http.Response
 	Body []byte
+
+	stream bool               // 流式响应，响应体未读取
+	cancel context.CancelFunc // 流式响应关闭时释放上下文
+	cached bool               // 响应来自缓存
+}
+
+// newResponse 用已有的响应体构造响应，用于缓存、回放等不经过网络的响应
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *Response {
+	return &Response{
+		Response: &http.Response{
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		},
+		Body: bytes.Clone(body),
+	}
+}
+
+// IsStream 是否为流式响应（响应体未读入 Body）
+func (r *Response) IsStream() bool {
+	return r.stream
+}
+
+// Reader 响应体的读取器，流式响应返回未读取的响应体，否则返回 Body 的读取器
+func (r *Response) Reader() io.Reader {
+	if r.stream {
+		return r.Response.Body
+	}
+	return bytes.NewReader(r.Body)
+}
+
+// Close 关闭流式响应，非流式响应无需关闭
+func (r *Response) Close() error {
+	if !r.stream {
+		return nil
+	}
+	err := r.Response.Body.Close()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	return err
 }
 
 // Text 响应的文本数据
@@ -51,3 +100,39 @@ func (r *Response) PrettyJSONString() (string, error) {
 	}
 	return buf.String(), nil
 }
+
+// DecodeOptions JSON 解码配置
+type DecodeOptions struct {
+	UseNumber bool // 数字解码为 json.Number 而不是 float64
+	Strict    bool // 严格模式，目标是结构体时不允许出现未知字段
+}
+
+// Decode 把响应的 JSON 数据解码到 v，支持结构体、切片、标量等任意类型
+func (r *Response) Decode(v any) error {
+	return r.DecodeWith(v, DecodeOptions{})
+}
+
+// DecodeWith 按配置把响应的 JSON 数据解码到 v
+func (r *Response) DecodeWith(v any, opts DecodeOptions) error {
+	dec := json.NewDecoder(bytes.NewReader(r.Body))
+	if opts.UseNumber {
+		dec.UseNumber()
+	}
+	if opts.Strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("解析JSON失败: %w", err)
+	}
+	if dec.More() {
+		return fmt.Errorf("解析JSON失败: 存在多余的数据")
+	}
+	return nil
+}
+
+// JSONValue 响应的 JSON 数据（顶层可以是对象、数组或标量）
+func (r *Response) JSONValue() (any, error) {
+	var v any
+	err := r.Decode(&v)
+	return v, err
+}
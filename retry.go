@@ -0,0 +1,182 @@
+package greqs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	_url "net/url"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultRetryStatusCodes 默认需要重试的状态码
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxAttempts int                                          // 最大尝试次数（包含第一次），小于 2 表示不重试
+	StatusCodes []int                                        // 需要重试的状态码，为空时使用 DefaultRetryStatusCodes
+	BaseDelay   time.Duration                                // 初始退避时间，默认 200ms，之后每次翻倍
+	MaxDelay    time.Duration                                // 最大退避时间，默认 10s，同样限制 Retry-After
+	OnRetry     func(attempt int, resp *Response, err error) // 每次重试前调用，attempt 为即将进行的第几次尝试
+}
+
+// ShouldRetry 判断本次结果是否需要重试（临时网络错误或命中状态码）
+func (p *RetryPolicy) ShouldRetry(resp *Response, err error) bool {
+	if err != nil {
+		return transient(err)
+	}
+	codes := p.StatusCodes
+	if len(codes) == 0 {
+		codes = DefaultRetryStatusCodes
+	}
+	return slices.Contains(codes, resp.StatusCode)
+}
+
+// transient 判断错误是否为可以重试的临时网络错误（连接被拒绝或重置、连接意外断开、超时等）。
+// 上下文取消、TLS 证书错误、响应体过长、认证失败等重试也不会成功的错误返回 false
+func transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	var alert tls.AlertError
+	if errors.As(err, &certErr) || errors.As(err, &alert) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) {
+		return true
+	}
+	// *url.Error 本身实现了 net.Error，需要判断它包装的错误
+	var urlErr *_url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Backoff 计算第 attempt 次尝试失败后的等待时间（指数退避 + 抖动，响应带有 Retry-After 时优先使用）
+func (p *RetryPolicy) Backoff(attempt int, resp *Response) time.Duration {
+	base, maxDelay := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = 200 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+
+	if resp != nil {
+		if d, ok := ParseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(d, maxDelay)
+		}
+	}
+
+	d := base << (attempt - 1)
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	// 在 [d/2, d] 之间随机，避免大量客户端同时重试
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// ParseRetryAfter 解析 Retry-After 响应头（秒数或 HTTP 日期）
+func ParseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// Retry 按重试策略执行 send，policy 为 nil 时只执行一次。
+// 每次尝试都会使用 req 的副本，请求体通过 GetBody 重放。
+func Retry(ctx context.Context, policy *RetryPolicy, req *http.Request, send func(req *http.Request) (*Response, error)) (*Response, error) {
+	if policy == nil || policy.MaxAttempts < 2 {
+		return send(req.WithContext(ctx))
+	}
+	if err := Rewindable(req); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		// 第一次尝试直接使用原请求体，之后通过 GetBody 重放
+		r := req.Clone(ctx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		resp, err := send(r)
+		if ctx.Err() != nil || attempt >= policy.MaxAttempts || !policy.ShouldRetry(resp, err) {
+			return resp, err
+		}
+
+		delay := policy.Backoff(attempt, resp)
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, resp, err)
+		}
+		if resp != nil {
+			resp.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+	}
+}
+
+// Rewindable 确保请求体可以重放，没有 GetBody 时把请求体读入内存
+func Rewindable(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// requestBody 通过 GetBody 读取完整的请求体，不影响之后的发送（需要先调用 Rewindable）
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody == nil {
+		return nil, nil
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,147 @@
+package greqs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	_url "net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 前 fails 次返回 503，之后回显请求体
+func newFlakyServer(fails int32) (*httptest.Server, *int32) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&count, 1) <= fails {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	return srv, &count
+}
+
+func TestWorker_Retry(t *testing.T) {
+	srv, count := newFlakyServer(2)
+	defer srv.Close()
+
+	var attempts []int
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetRetry(&RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		OnRetry: func(attempt int, resp *Response, err error) {
+			attempts = append(attempts, attempt)
+		},
+	})
+
+	resp, err := worker.Post(srv.URL, nil, A{"name": "Greqs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Text() != `{"name":"Greqs"}` {
+		t.Errorf("unexpected response: %d %s", resp.StatusCode, resp.Text())
+	}
+	if *count != 3 || len(attempts) != 2 || attempts[0] != 2 || attempts[1] != 3 {
+		t.Errorf("count = %d, attempts = %v", *count, attempts)
+	}
+}
+
+func TestRequest_RetryExhausted(t *testing.T) {
+	srv, count := newFlakyServer(5)
+	defer srv.Close()
+
+	req := &Request{Method: "GET", Url: srv.URL, Retry: &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}}
+	resp, err := req.Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || *count != 2 {
+		t.Errorf("status = %d, count = %d", resp.StatusCode, *count)
+	}
+}
+
+func TestRetry_OneShotBody(t *testing.T) {
+	srv, _ := newFlakyServer(1)
+	defer srv.Close()
+
+	// io.NopCloser 包装后 http.NewRequest 不会设置 GetBody
+	req, _ := http.NewRequest("POST", srv.URL, io.NopCloser(strings.NewReader("payload")))
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetRetry(&RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	resp, err := worker.Go(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "payload" {
+		t.Errorf("body = %q, want payload", resp.Text())
+	}
+}
+
+func TestWorker_RetryPermanentError(t *testing.T) {
+	srv, count := newFlakyServer(0)
+	defer srv.Close()
+
+	// 响应体过长重试也不会成功，只请求一次
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetMaxBodySize(10)
+	worker.SetRetry(&RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond})
+	_, err := worker.Post(srv.URL, nil, A{"name": "a long enough body"})
+	var tooLarge *BodyTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("err = %v", err)
+	}
+	if *count != 1 {
+		t.Errorf("count = %d, want 1", *count)
+	}
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	p := &RetryPolicy{}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&_url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, true},
+		{&_url.Error{Op: "Get", Err: io.EOF}, true},
+		{io.ErrUnexpectedEOF, true},
+		{&_url.Error{Op: "Get", Err: context.DeadlineExceeded}, true},
+		{context.Canceled, false},
+		{&_url.Error{Op: "Get", Err: errors.New("证书公钥与固定的指纹不匹配")}, false},
+		{&BodyTooLargeError{Limit: 10}, false},
+		{ErrNoProxy, false},
+		{&OAuth2Error{Code: "invalid_client"}, false},
+	}
+	for _, tt := range tests {
+		if got := p.ShouldRetry(nil, tt.err); got != tt.want {
+			t.Errorf("%v: got %v", tt.err, got)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		d := p.Backoff(attempt, nil)
+		if d < want/2 || d > want {
+			t.Errorf("attempt %d: backoff %v not in [%v, %v]", attempt, d, want/2, want)
+		}
+	}
+
+	resp := &Response{Response: &http.Response{Header: http.Header{"Retry-After": {"5"}}}}
+	if d := p.Backoff(1, resp); d != time.Second {
+		t.Errorf("Retry-After should be capped by MaxDelay, got %v", d)
+	}
+	if d, ok := ParseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); !ok || d < 59*time.Minute {
+		t.Errorf("ParseRetryAfter date = %v, %v", d, ok)
+	}
+}
@@ -0,0 +1,195 @@
+package greqs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultSegments 分段下载的默认分段数
+const DefaultSegments = 4
+
+// segment 一个字节范围 [start, end]
+type segment struct {
+	start, end int64
+}
+
+// DownloadSegmented 把文件分成 segments 段并发下载，每段独立重试（次数取 Worker 重试策略的 MaxAttempts，默认 3），
+// 按偏移写入 path + ".part"，完成并校验后原子重命名。服务端不支持 Range 或没有返回 ETag / Last-Modified 时退化为 Download。
+// 与 Download 一样，Worker 的超时作为每段的空闲超时。
+func (w *Worker) DownloadSegmented(ctx context.Context, url, path string, segments int, opts *DownloadOptions) (*DownloadResult, error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	if segments <= 0 {
+		segments = DefaultSegments
+	}
+
+	head, err := w.SendContext(ctx, http.MethodHead, url, opts.Headers, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	size := head.ContentLength
+	if head.StatusCode != http.StatusOK || !strings.Contains(head.Header.Get("Accept-Ranges"), "bytes") || size <= 0 || segments == 1 {
+		return w.Download(ctx, url, path, opts)
+	}
+	if opts.Size > 0 && size != opts.Size {
+		return nil, fmt.Errorf("文件大小为 %d，期望 %d", size, opts.Size)
+	}
+	// 没有校验值时无法通过 If-Range 保证各段来自同一版本的文件，退化为单线程下载
+	validator := (&partMeta{ETag: head.Header.Get("ETag"), LastModified: head.Header.Get("Last-Modified")}).validator()
+	if validator == "" {
+		return w.Download(ctx, url, path, opts)
+	}
+
+	partPath := path + ".part"
+	file, err := os.Create(partPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	progress := &segmentProgress{total: size, fn: opts.Progress}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for _, seg := range splitSegments(size, segments) {
+		wg.Add(1)
+		go func(seg segment) {
+			defer wg.Done()
+			if err := w.downloadSegment(ctx, url, validator, file, seg, opts.Headers, progress); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(seg)
+	}
+	wg.Wait()
+	file.Close()
+
+	if firstErr != nil {
+		os.Remove(partPath)
+		return nil, firstErr
+	}
+	n, err := verifyFile(partPath, opts)
+	if err != nil {
+		os.Remove(partPath)
+		return nil, err
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return nil, err
+	}
+	return &DownloadResult{Path: path, Size: n}, nil
+}
+
+// splitSegments 把 [0, size) 平均分成 n 段
+func splitSegments(size int64, n int) []segment {
+	if int64(n) > size {
+		n = int(size)
+	}
+	segs := make([]segment, 0, n)
+	step := size / int64(n)
+	for i := 0; i < n; i++ {
+		start := int64(i) * step
+		end := start + step - 1
+		if i == n-1 {
+			end = size - 1
+		}
+		segs = append(segs, segment{start, end})
+	}
+	return segs
+}
+
+// downloadSegment 下载一段，失败时从已写入的位置继续
+func (w *Worker) downloadSegment(ctx context.Context, url, validator string, file *os.File, seg segment, headers S, progress *segmentProgress) error {
+	return w.resume(ctx, 3, func() error {
+		n, err := w.fetchSegment(ctx, url, validator, file, seg, headers, progress)
+		seg.start += n
+		return err
+	})
+}
+
+// fetchSegment 请求一段并写入文件，返回写入的字节数
+func (w *Worker) fetchSegment(ctx context.Context, url, validator string, file *os.File, seg segment, headers S, progress *segmentProgress) (int64, error) {
+	req, err := MakeGetRequest(url, headers)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", seg.start, seg.end))
+	req.Header.Set("If-Range", validator)
+
+	resp, err := w.goDownload(ctx, req)
+	if err != nil {
+		return 0, &errInterrupted{err: err}
+	}
+	defer resp.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		err := fmt.Errorf("分段 %d-%d 下载失败，状态码 %d", seg.start, seg.end, resp.StatusCode)
+		policy := w.GetRetry()
+		if policy == nil {
+			policy = &RetryPolicy{}
+		}
+		if policy.ShouldRetry(resp, nil) {
+			return 0, &errInterrupted{err: err, resp: resp}
+		}
+		return 0, err
+	}
+	if start, _, ok := ParseContentRange(resp.Header.Get("Content-Range")); !ok || start != seg.start {
+		return 0, fmt.Errorf("无效的 Content-Range: %s", resp.Header.Get("Content-Range"))
+	}
+
+	want := seg.end - seg.start + 1
+	n, err := io.Copy(&offsetWriter{file: file, offset: seg.start, progress: progress}, io.LimitReader(resp.Reader(), want))
+	if err == nil && n != want {
+		err = fmt.Errorf("分段 %d-%d 下载不完整", seg.start, seg.end)
+	}
+	if err != nil {
+		return n, &errInterrupted{err: err}
+	}
+	return n, nil
+}
+
+// offsetWriter 从 offset 开始写入文件，多个分段可以并发写入同一文件
+type offsetWriter struct {
+	file     *os.File
+	offset   int64
+	progress *segmentProgress
+}
+
+func (o *offsetWriter) Write(b []byte) (int, error) {
+	n, err := o.file.WriteAt(b, o.offset)
+	o.offset += int64(n)
+	o.progress.add(int64(n))
+	return n, err
+}
+
+// segmentProgress 汇总所有分段的进度
+type segmentProgress struct {
+	mu    sync.Mutex
+	done  int64
+	total int64
+	fn    func(done, total int64)
+}
+
+func (p *segmentProgress) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	if p.fn != nil {
+		p.fn(p.done, p.total)
+	}
+}
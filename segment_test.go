@@ -0,0 +1,137 @@
+package greqs
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorker_DownloadSegmented(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10001)
+
+	// 每个 Range 第一次请求返回 503，验证分段独立重试
+	var mu sync.Mutex
+	seen := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if rng := r.Header.Get("Range"); rng != "" {
+			mu.Lock()
+			seen[rng]++
+			first := seen[rng] == 1
+			mu.Unlock()
+			if first {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	var done int64
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	path := filepath.Join(t.TempDir(), "file.bin")
+	result, err := worker.DownloadSegmented(context.Background(), srv.URL, path, 4, &DownloadOptions{
+		Progress: func(d, total int64) { done = d },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, content) || result.Size != int64(len(content)) || done != int64(len(content)) {
+		t.Errorf("size = %d, progress = %d", result.Size, done)
+	}
+	if len(seen) != 4 {
+		t.Errorf("ranges = %v", seen)
+	}
+}
+
+func TestWorker_DownloadSegmentedRetry(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 100)
+	var ranged int32
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("Range") != "" {
+			atomic.AddInt32(&ranged, 1)
+			w.WriteHeader(status)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	// 每段最多请求 MaxAttempts 次，不会与 Worker 的重试叠加
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetRetry(&RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	if _, err := worker.DownloadSegmented(context.Background(), srv.URL, filepath.Join(t.TempDir(), "file.bin"), 2, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	// 一段失败后会取消其他分段，所以最多 2 × 2 次
+	if n := atomic.LoadInt32(&ranged); n < 2 || n > 4 {
+		t.Errorf("分段请求了 %d 次", n)
+	}
+
+	// 404 不会重试，每段只请求一次
+	status = http.StatusNotFound
+	atomic.StoreInt32(&ranged, 0)
+	if _, err := worker.DownloadSegmented(context.Background(), srv.URL, filepath.Join(t.TempDir(), "file.bin"), 2, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := atomic.LoadInt32(&ranged); n < 1 || n > 2 {
+		t.Errorf("分段请求了 %d 次", n)
+	}
+}
+
+func TestWorker_DownloadSegmentedFallback(t *testing.T) {
+	content := []byte("no ranges here")
+	tests := map[string]http.HandlerFunc{
+		"不支持 Range": func(w http.ResponseWriter, r *http.Request) {
+			w.Write(content)
+		},
+		// 支持 Range 但没有 ETag 和 Last-Modified
+		"没有校验值": func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "file.txt", time.Time{}, bytes.NewReader(content))
+		},
+	}
+	for name, handler := range tests {
+		var ranged atomic.Bool
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "" {
+				ranged.Store(true)
+			}
+			handler(w, r)
+		}))
+
+		worker := NewWorker("", 5*time.Second, nil, nil)
+		path := filepath.Join(t.TempDir(), "file.txt")
+		if _, err := worker.DownloadSegmented(context.Background(), srv.URL, path, 4, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, _ := os.ReadFile(path)
+		if !bytes.Equal(got, content) || ranged.Load() {
+			t.Errorf("%s: got %q, ranged = %v", name, got, ranged.Load())
+		}
+		srv.Close()
+	}
+}
+
+func TestSplitSegments(t *testing.T) {
+	segs := splitSegments(10, 3)
+	want := []segment{{0, 2}, {3, 5}, {6, 9}}
+	for i := range want {
+		if segs[i] != want[i] {
+			t.Errorf("segs = %v", segs)
+		}
+	}
+	if len(splitSegments(2, 4)) != 2 {
+		t.Error("segments should not exceed size")
+	}
+}
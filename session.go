@@ -0,0 +1,146 @@
+package greqs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	_url "net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Jar 可以保存到文件的 Cookie 容器，Cookie 的匹配规则由标准库的 cookiejar 实现
+type Jar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	entries map[string]jarEntry // 记录设置过的 Cookie，用于保存
+}
+
+// jarEntry 一条 Cookie 及其来源网址
+type jarEntry struct {
+	Url    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// NewJar 创建 Cookie 容器
+func NewJar() *Jar {
+	jar, _ := cookiejar.New(nil)
+	return &Jar{jar: jar, entries: map[string]jarEntry{}}
+}
+
+// SetCookies 实现 http.CookieJar
+func (j *Jar) SetCookies(u *_url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.jar.SetCookies(u, cookies)
+	origin := (&_url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+	for _, c := range cookies {
+		domain := c.Domain
+		if domain == "" {
+			domain = u.Hostname()
+		}
+		path := c.Path
+		if !strings.HasPrefix(path, "/") {
+			path = defaultCookiePath(u.Path)
+		}
+		key := domain + "|" + path + "|" + c.Name
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(time.Now())) {
+			delete(j.entries, key)
+			continue
+		}
+		// MaxAge 是相对时间，转换为绝对的过期时间后再记录
+		cc := *c
+		cc.Path = path
+		if cc.MaxAge > 0 {
+			cc.Expires = time.Now().Add(time.Duration(cc.MaxAge) * time.Second)
+			cc.MaxAge = 0
+		}
+		j.entries[key] = jarEntry{Url: origin, Cookie: &cc}
+	}
+}
+
+// defaultCookiePath Cookie 没有 Path 属性时的默认路径（RFC 6265 5.1.4），即请求路径所在的目录
+func defaultCookiePath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 || path[0] != '/' {
+		return "/"
+	}
+	return path[:i]
+}
+
+// Cookies 实现 http.CookieJar
+func (j *Jar) Cookies(u *_url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jar.Cookies(u)
+}
+
+// Save 把未过期的 Cookie 以 JSON 形式保存到文件
+func (j *Jar) Save(path string) error {
+	j.mu.Lock()
+	entries := make([]jarEntry, 0, len(j.entries))
+	now := time.Now()
+	for _, e := range j.entries {
+		if e.Cookie.Expires.IsZero() || e.Cookie.Expires.After(now) {
+			entries = append(entries, e)
+		}
+	}
+	j.mu.Unlock()
+
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
+
+// Load 从 Save 保存的文件中加载 Cookie
+func (j *Jar) Load(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var entries []jarEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		u, err := _url.Parse(e.Url)
+		if err != nil {
+			return err
+		}
+		j.SetCookies(u, []*http.Cookie{e.Cookie})
+	}
+	return nil
+}
+
+// Session 会话，在多次请求之间保持 Cookie、默认请求头和基础网址
+type Session struct {
+	*Worker
+	Jar *Jar
+}
+
+// NewSession 创建会话，baseUrl 为空时请求需要使用完整网址
+func NewSession(baseUrl string, headers S) (*Session, error) {
+	jar := NewJar()
+	worker := NewWorker("", 0, nil, nil)
+	if err := worker.SetBaseUrl(baseUrl); err != nil {
+		return nil, err
+	}
+	worker.SetHeaders(headers)
+	worker.SetJar(jar)
+	return &Session{Worker: worker, Jar: jar}, nil
+}
+
+// SaveCookies 保存会话的 Cookie 到文件
+func (s *Session) SaveCookies(path string) error {
+	return s.Jar.Save(path)
+}
+
+// LoadCookies 从文件加载 Cookie 到会话
+func (s *Session) LoadCookies(path string) error {
+	return s.Jar.Load(path)
+}
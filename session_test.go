@@ -0,0 +1,97 @@
+package greqs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+// 本地登录服务，/login 设置 Cookie，/profile 校验 Cookie 和请求头
+func newLoginServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "token", Value: "secret", Path: "/", MaxAge: 3600})
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("token")
+		if err != nil || c.Value != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("User-Agent")))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestSession(t *testing.T) {
+	srv := newLoginServer()
+	defer srv.Close()
+
+	sess, err := NewSession(srv.URL, S{"User-Agent": "Greqs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := sess.Get("/profile", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status = %d before login", resp.StatusCode)
+	}
+	if _, err := sess.Get("/login", nil); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := sess.Get("/profile", S{"User-Agent": "Custom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Text() != "Custom" {
+		t.Errorf("unexpected response: %d %s", resp.StatusCode, resp.Text())
+	}
+
+	// 保存后在新会话中加载，模拟进程重启
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := sess.SaveCookies(path); err != nil {
+		t.Fatal(err)
+	}
+	sess2, _ := NewSession(srv.URL, S{"User-Agent": "Greqs"})
+	if err := sess2.LoadCookies(path); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = sess2.Get("/profile", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Text() != "Greqs" {
+		t.Errorf("after load: %d %s", resp.StatusCode, resp.Text())
+	}
+}
+
+func TestJar_DefaultPath(t *testing.T) {
+	// 没有 Path 属性的同名 Cookie 来自不同目录，保存后都能按各自的默认路径恢复
+	jar := NewJar()
+	for _, set := range []struct{ url, value string }{
+		{"http://example.com/a/login", "a"},
+		{"http://example.com/b/login", "b"},
+	} {
+		u, _ := url.Parse(set.url)
+		jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: set.value}})
+	}
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := jar.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewJar()
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	for page, want := range map[string]string{"/a/profile": "a", "/b/profile": "b", "/profile": ""} {
+		u, _ := url.Parse("http://example.com" + page)
+		var got string
+		for _, c := range loaded.Cookies(u) {
+			got += c.Value
+		}
+		if got != want {
+			t.Errorf("%s: cookies = %q, want %q", page, got, want)
+		}
+	}
+}
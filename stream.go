@@ -0,0 +1,153 @@
+package greqs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// BodyTooLargeError 响应体超过了允许的最大长度
+type BodyTooLargeError struct {
+	Limit int64 // 允许的最大长度
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("响应体超过最大长度 %d 字节", e.Limit)
+}
+
+// DoLimit 发送请求，获取响应，响应体最多读取 limit 字节，超过时返回 *BodyTooLargeError，limit 不大于 0 时不限制
+func DoLimit(cli *http.Client, req *http.Request, limit int64) (*Response, error) {
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if limit <= 0 {
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Response: resp, Body: bodyBytes}, nil
+	}
+
+	if resp.ContentLength > limit {
+		return nil, &BodyTooLargeError{Limit: limit}
+	}
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(bodyBytes)) > limit {
+		return nil, &BodyTooLargeError{Limit: limit}
+	}
+	return &Response{Response: resp, Body: bodyBytes}, nil
+}
+
+// DoStream 发送请求，不读取响应体，调用方需要通过 Response.Reader 读取并调用 Response.Close
+func DoStream(cli *http.Client, req *http.Request) (*Response, error) {
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Response: resp, stream: true}, nil
+}
+
+// fetch 在超时内发送一次请求。流式模式下超时覆盖整个读取过程，直到 Response.Close 才释放
+func fetch(cli *http.Client, req *http.Request, timeout time.Duration, stream bool, maxBodySize int64) (*Response, error) {
+	ctx, cancel := WithTimeout(req.Context(), timeout)
+	req = req.WithContext(ctx)
+	if stream {
+		resp, err := DoStream(cli, req)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp.cancel = cancel
+		return resp, nil
+	}
+	defer cancel()
+	return DoLimit(cli, req, maxBodySize)
+}
+
+// fetchIdle 以流式模式发送一次请求，timeout 为空闲超时：等待响应头或读取响应体时超过 timeout 没有收到数据就取消请求，
+// 下载大文件时不会因为总耗时超过超时时间而中断
+func fetchIdle(cli *http.Client, req *http.Request, timeout time.Duration) (*Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	if timeout <= 0 {
+		resp, err := DoStream(cli, req.WithContext(ctx))
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp.cancel = cancel
+		return resp, nil
+	}
+
+	timer := time.AfterFunc(timeout, cancel)
+	stop := func() {
+		timer.Stop()
+		cancel()
+	}
+	resp, err := DoStream(cli, req.WithContext(ctx))
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	resp.Response.Body = &idleBody{ReadCloser: resp.Response.Body, timer: timer, timeout: timeout}
+	resp.cancel = stop
+	return resp, nil
+}
+
+// idleBody 每次读到数据时重置空闲计时器
+type idleBody struct {
+	io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+// withStream 执行 fn，结束后一定关闭响应
+func withStream(resp *Response, err error, fn func(resp *Response) error) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	return fn(resp)
+}
+
+// SendStream 以流式模式发送请求，响应体不会被读入内存，用完后必须调用 Response.Close
+func SendStream(ctx context.Context, method, url string, opts *Options) (*Response, error) {
+	return send(ctx, method, url, opts, true)
+}
+
+// DoStream 以流式模式执行请求，用完后必须调用 Response.Close
+func (r *Request) DoStream(ctx context.Context) (*Response, error) {
+	return SendStream(ctx, r.Method, r.Url, r.Options())
+}
+
+// Stream 以流式模式执行请求，fn 返回后自动关闭响应
+func (r *Request) Stream(ctx context.Context, fn func(resp *Response) error) error {
+	resp, err := r.DoStream(ctx)
+	return withStream(resp, err, fn)
+}
+
+// GoStream 以流式模式发送请求，用完后必须调用 Response.Close
+func (w *Worker) GoStream(ctx context.Context, req *http.Request) (*Response, error) {
+	return w.do(ctx, req, fetchStream, nil)
+}
+
+// Stream 以流式模式发送请求，fn 返回后自动关闭响应
+func (w *Worker) Stream(ctx context.Context, req *http.Request, fn func(resp *Response) error) error {
+	resp, err := w.GoStream(ctx, req)
+	return withStream(resp, err, fn)
+}
@@ -0,0 +1,82 @@
+package greqs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 本地大文件服务，分块写出 size 字节（不带 Content-Length）
+func newBigServer(size int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := strings.Repeat("x", 1024)
+		for n := 0; n < size; n += len(chunk) {
+			io.WriteString(w, chunk[:min(len(chunk), size-n)])
+			w.(http.Flusher).Flush()
+		}
+	}))
+}
+
+func TestWorker_Stream(t *testing.T) {
+	srv := newBigServer(1 << 20)
+	defer srv.Close()
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetMaxBodySize(1024)
+	req, _ := MakeGetRequest(srv.URL, nil)
+
+	var n int64
+	err := worker.Stream(context.Background(), req, func(resp *Response) error {
+		if !resp.IsStream() || resp.Body != nil {
+			t.Error("expected stream response")
+		}
+		var err error
+		n, err = io.Copy(io.Discard, resp.Reader())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1<<20 {
+		t.Errorf("read %d bytes", n)
+	}
+
+	// 缓冲模式超过最大长度
+	req, _ = MakeGetRequest(srv.URL, nil)
+	_, err = worker.Go(req)
+	var tooLarge *BodyTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 1024 {
+		t.Errorf("err = %v, want *BodyTooLargeError", err)
+	}
+}
+
+func TestRequest_MaxBodySize(t *testing.T) {
+	srv := newBigServer(100)
+	defer srv.Close()
+
+	req := &Request{Method: "GET", Url: srv.URL, MaxBodySize: 100}
+	resp, err := req.Do()
+	if err != nil || len(resp.Body) != 100 {
+		t.Fatalf("exact limit: %v", err)
+	}
+	req.MaxBodySize = 99
+	if _, err := req.Do(); err == nil {
+		t.Error("expected BodyTooLargeError")
+	}
+
+	err = req.Stream(context.Background(), func(resp *Response) error {
+		b, err := io.ReadAll(resp.Reader())
+		if len(b) != 100 {
+			t.Errorf("stream read %d bytes", len(b))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,155 @@
+package greqs
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSOptions TLS 配置，文件与内容两种形式可以同时使用
+type TLSOptions struct {
+	CAFiles        []string // 根证书文件（PEM），追加到系统根证书中
+	CAPEM          []byte   // 根证书内容（PEM）
+	CertFile       string   // 客户端证书文件（PEM）
+	KeyFile        string   // 客户端私钥文件（PEM）
+	CertPEM        []byte   // 客户端证书内容（PEM）
+	KeyPEM         []byte   // 客户端私钥内容（PEM）
+	PKCS12File     string   // 客户端证书文件（PKCS#12，.p12/.pfx）
+	PKCS12         []byte   // 客户端证书内容（PKCS#12）
+	PKCS12Password string   // PKCS#12 密码
+	MinVersion     uint16   // 最低 TLS 版本，例如 tls.VersionTLS12，0 表示使用默认值
+	ServerName     string   // 覆盖 SNI 以及证书校验使用的主机名
+	// 固定的证书公钥指纹（SPKI 的 SHA-256，base64 编码，可带 "sha256/" 前缀），校验通过的证书链中任一证书（包括根证书）匹配即通过，
+	// 跳过校验时只匹配服务端的叶子证书
+	Pins               []string
+	InsecureSkipVerify bool // 跳过证书校验，仅用于测试环境（公钥固定仍然生效）
+}
+
+// PublicKeyPin 计算证书公钥指纹，格式为 sha256/<base64>
+func PublicKeyPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Config 生成 tls.Config
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         o.MinVersion,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if len(o.CAFiles) > 0 || len(o.CAPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		var bundles [][]byte
+		for _, file := range o.CAFiles {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("读取根证书失败: %w", err)
+			}
+			bundles = append(bundles, data)
+		}
+		if len(o.CAPEM) > 0 {
+			bundles = append(bundles, o.CAPEM)
+		}
+		for _, data := range bundles {
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, errors.New("根证书中没有有效的 PEM 证书")
+			}
+		}
+		cfg.RootCAs = pool
+	}
+
+	certs, err := o.certificates()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Certificates = certs
+
+	if len(o.Pins) > 0 {
+		pins := make(map[string]bool, len(o.Pins))
+		for _, pin := range o.Pins {
+			pins["sha256/"+strings.TrimPrefix(pin, "sha256/")] = true
+		}
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			// 服务端发送的证书列表没有经过校验，可以附带任意证书，只能匹配校验通过的证书链；
+			// 跳过校验时只匹配服务端的叶子证书（握手时服务端已证明持有它的私钥）
+			var certs []*x509.Certificate
+			if o.InsecureSkipVerify {
+				if len(cs.PeerCertificates) > 0 {
+					certs = cs.PeerCertificates[:1]
+				}
+			} else {
+				for _, chain := range cs.VerifiedChains {
+					certs = append(certs, chain...)
+				}
+			}
+			for _, cert := range certs {
+				if pins[PublicKeyPin(cert)] {
+					return nil
+				}
+			}
+			return errors.New("证书公钥与固定的指纹不匹配")
+		}
+	}
+	return cfg, nil
+}
+
+// certificates 加载客户端证书
+func (o *TLSOptions) certificates() ([]tls.Certificate, error) {
+	var certs []tls.Certificate
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(o.CertPEM) > 0 || len(o.KeyPEM) > 0 {
+		cert, err := tls.X509KeyPair(o.CertPEM, o.KeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	data := o.PKCS12
+	if o.PKCS12File != "" {
+		var err error
+		if data, err = os.ReadFile(o.PKCS12File); err != nil {
+			return nil, fmt.Errorf("读取 PKCS#12 失败: %w", err)
+		}
+	}
+	if len(data) > 0 {
+		cert, err := LoadPKCS12(data, o.PKCS12Password)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// cacheKey 按配置的内容生成缓存键，内容相同的配置共用一个 Transport
+func (o *TLSOptions) cacheKey() (string, error) {
+	if o == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,280 @@
+package greqs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// 测试用的证书，parent 为空时自签名
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, parent *testCert, name string, isCA bool, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if !isCA {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		tmpl.DNSNames = []string{name}
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCert() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+// 使用指定服务端证书的 TLS 服务，响应中返回客户端证书的 CN
+func newTLSServer(t *testing.T, cert *testCert, cfg *tls.Config) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := ""
+		if len(r.TLS.PeerCertificates) > 0 {
+			name = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		w.Write([]byte(r.TLS.ServerName + "|" + name))
+	}))
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	if len(cfg.Certificates) == 0 {
+		cfg.Certificates = []tls.Certificate{cert.tlsCert()}
+	}
+	srv.TLS = cfg
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // 握手失败是预期的
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTLSOptions_CustomCA(t *testing.T) {
+	ca := newTestCert(t, nil, "greqs-ca", true, 0)
+	srv := newTLSServer(t, newTestCert(t, ca, "internal.example", false, x509.ExtKeyUsageServerAuth), nil)
+
+	w := NewWorker("", 5*time.Second, nil, nil)
+	if _, err := w.Get(srv.URL, nil); err == nil {
+		t.Fatal("未信任私有 CA 时应该失败")
+	}
+
+	// 证书只对 internal.example 有效，通过 SNI 覆盖访问 127.0.0.1
+	w.SetTLS(&TLSOptions{CAPEM: ca.certPEM, ServerName: "internal.example"})
+	resp, err := w.Get(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "internal.example|" {
+		t.Fatalf("响应 %q", resp.Text())
+	}
+
+	file := t.TempDir() + "/ca.pem"
+	if err := os.WriteFile(file, ca.certPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w.SetTLS(&TLSOptions{CAFiles: []string{file}})
+	if _, err := w.Get(srv.URL, nil); err == nil {
+		t.Fatal("主机名与证书不匹配时应该失败")
+	}
+}
+
+func TestTLSOptions_ClientCert(t *testing.T) {
+	ca := newTestCert(t, nil, "greqs-ca", true, 0)
+	client := newTestCert(t, ca, "greqs-client", false, x509.ExtKeyUsageClientAuth)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	srv := newTLSServer(t, newTestCert(t, ca, "internal.example", false, x509.ExtKeyUsageServerAuth), &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	})
+
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.SetTLS(&TLSOptions{CAPEM: ca.certPEM, ServerName: "internal.example"})
+	if _, err := w.Get(srv.URL, nil); err == nil {
+		t.Fatal("没有客户端证书时应该失败")
+	}
+
+	w.SetTLS(&TLSOptions{CAPEM: ca.certPEM, ServerName: "internal.example", CertPEM: client.certPEM, KeyPEM: client.keyPEM})
+	resp, err := w.Get(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "internal.example|greqs-client" {
+		t.Fatalf("响应 %q", resp.Text())
+	}
+}
+
+func TestTLSOptions_PKCS12(t *testing.T) {
+	crtPEM, err := os.ReadFile("testdata/client.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(crtPEM)
+	want, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// client.p12 为 OpenSSL 3 默认格式（PBES2 + AES-256-CBC），client-3des.p12 为 pbeWithSHAAnd3-KeyTripleDES-CBC
+	for _, file := range []string{"testdata/client.p12", "testdata/client-3des.p12"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := LoadPKCS12(data, "secret")
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if !cert.Leaf.Equal(want) {
+			t.Fatalf("%s: 证书不一致", file)
+		}
+		if _, err := LoadPKCS12(data, "wrong"); !errors.Is(err, ErrPKCS12Password) {
+			t.Fatalf("%s: 密码错误时返回 %v", file, err)
+		}
+	}
+
+	// 用 PKCS#12 中的证书完成双向认证
+	ca := newTestCert(t, nil, "greqs-ca", true, 0)
+	pool := x509.NewCertPool()
+	pool.AddCert(want)
+	srv := newTLSServer(t, newTestCert(t, ca, "internal.example", false, x509.ExtKeyUsageServerAuth), &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	})
+	opts := &TLSOptions{
+		CAPEM:          ca.certPEM,
+		ServerName:     "internal.example",
+		PKCS12File:     "testdata/client.p12",
+		PKCS12Password: "secret",
+	}
+	resp, err := SendContext(context.Background(), "GET", srv.URL, &Options{TLS: opts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "internal.example|greqs-client" {
+		t.Fatalf("响应 %q", resp.Text())
+	}
+}
+
+func TestTLSOptions_Pins(t *testing.T) {
+	ca := newTestCert(t, nil, "greqs-ca", true, 0)
+	server := newTestCert(t, ca, "internal.example", false, x509.ExtKeyUsageServerAuth)
+	other := newTestCert(t, nil, "other", true, 0)
+	srv := newTLSServer(t, server, nil)
+
+	tests := []struct {
+		name string
+		opts *TLSOptions
+		ok   bool
+	}{
+		{"叶子证书", &TLSOptions{InsecureSkipVerify: true, Pins: []string{PublicKeyPin(server.cert)}}, true},
+		{"根证书", &TLSOptions{CAPEM: ca.certPEM, ServerName: "internal.example", Pins: []string{PublicKeyPin(ca.cert)}}, true},
+		{"跳过校验时根证书不在链中", &TLSOptions{InsecureSkipVerify: true, Pins: []string{PublicKeyPin(ca.cert)}}, false},
+		{"不带前缀", &TLSOptions{InsecureSkipVerify: true, Pins: []string{PublicKeyPin(server.cert)[len("sha256/"):]}}, true},
+		{"不匹配", &TLSOptions{InsecureSkipVerify: true, Pins: []string{PublicKeyPin(other.cert)}}, false},
+		{"跳过校验", &TLSOptions{InsecureSkipVerify: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GetTLSClient("", 5*time.Second, tt.opts).Get(srv.URL)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v", err)
+			}
+		})
+	}
+}
+
+// 攻击者使用自己的叶子证书，并在证书链后面附带固定的证书，不能通过公钥固定
+func TestTLSOptions_PinsAppendedCert(t *testing.T) {
+	ca := newTestCert(t, nil, "greqs-ca", true, 0)
+	legit := newTestCert(t, ca, "internal.example", false, x509.ExtKeyUsageServerAuth)
+	for _, attacker := range []*testCert{
+		newTestCert(t, nil, "internal.example", false, x509.ExtKeyUsageServerAuth), // 自签名
+		newTestCert(t, ca, "internal.example", false, x509.ExtKeyUsageServerAuth),  // 持有同一 CA 签发的合法证书
+	} {
+		chain := attacker.tlsCert()
+		chain.Certificate = append(chain.Certificate, legit.cert.Raw)
+		srv := newTLSServer(t, attacker, &tls.Config{Certificates: []tls.Certificate{chain}})
+
+		pins := []string{PublicKeyPin(legit.cert)}
+		for _, opts := range []*TLSOptions{
+			{InsecureSkipVerify: true, Pins: pins},
+			{CAPEM: ca.certPEM, ServerName: "internal.example", Pins: pins},
+		} {
+			if _, err := GetTLSClient("", 5*time.Second, opts).Get(srv.URL); err == nil {
+				t.Fatalf("附带固定证书的攻击者服务不应该通过校验 %+v", opts)
+			}
+		}
+	}
+}
+
+func TestTLSOptions_MinVersion(t *testing.T) {
+	ca := newTestCert(t, nil, "greqs-ca", true, 0)
+	srv := newTLSServer(t, newTestCert(t, ca, "internal.example", false, x509.ExtKeyUsageServerAuth), &tls.Config{MaxVersion: tls.VersionTLS12})
+
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.SetTLS(&TLSOptions{InsecureSkipVerify: true, MinVersion: tls.VersionTLS13})
+	if _, err := w.Get(srv.URL, nil); err == nil {
+		t.Fatal("服务端不支持最低版本时应该失败")
+	}
+	w.SetTLS(&TLSOptions{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12})
+	if _, err := w.Get(srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSOptions_Invalid(t *testing.T) {
+	w := NewWorker("", time.Second, nil, nil)
+	w.SetTLS(&TLSOptions{CAPEM: []byte("not a pem")})
+	if _, err := w.Get("https://"+net.JoinHostPort("127.0.0.1", "1"), nil); err == nil {
+		t.Fatal("无效的根证书应该返回错误")
+	}
+	if _, err := (&TLSOptions{PKCS12File: "testdata/client.p12", PKCS12Password: "wrong"}).Config(); !errors.Is(err, ErrPKCS12Password) {
+		t.Fatalf("err = %v", err)
+	}
+}
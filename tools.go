@@ -1,8 +1,7 @@
 package greqs
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
@@ -32,71 +31,113 @@ func SetHeaders(req *http.Request, headers S) {
 	}
 }
 
-// MakeGetRequest 创建 GET 请求
-func MakeGetRequest(url string, headers S) (*http.Request, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// 支持的请求方法
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// CheckMethod 校验请求方法，返回大写形式的方法名
+func CheckMethod(method string) (string, error) {
+	method = strings.ToUpper(method)
+	if !methods[method] {
+		return "", fmt.Errorf("不支持的请求方法 %s", method)
+	}
+	return method, nil
+}
+
+// MakeRequest 创建任意方法的请求（data 不为空时使用 JSON 形式，否则 form 不为空时使用表单形式，都为空则没有请求体）
+func MakeRequest(method, url string, headers S, data A, form S) (*http.Request, error) {
+	return MakeBodyRequest(method, url, headers, &Body{Data: data, Form: form})
+}
+
+// MakeBodyRequest 创建任意方法、任意请求体的请求，body 为空时没有请求体
+func MakeBodyRequest(method, url string, headers S, body *Body) (*http.Request, error) {
+	method, err := CheckMethod(method)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, contentType, err := body.Encode()
 	if err != nil {
 		return nil, err
 	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if m := body.Multipart; m != nil && reader != nil && m.Replayable() {
+		req.GetBody = func() (io.ReadCloser, error) {
+			r, _ := m.Reader()
+			return r, nil
+		}
+	}
 	SetHeaders(req, headers)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 	return req, nil
 }
 
-// MakePostRequest 创建 POST 请求（JSON 形式）
-func MakePostRequest(url string, headers S, data A) (*http.Request, error) {
-	body, _ := json.Marshal(data)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+// MakeGetRequest 创建 GET 请求
+func MakeGetRequest(url string, headers S) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 	SetHeaders(req, headers)
-	req.Header.Set("Content-Type", "application/json")
 	return req, nil
 }
 
+// MakePostRequest 创建 POST 请求（JSON 形式）
+func MakePostRequest(url string, headers S, data A) (*http.Request, error) {
+	return MakeBodyRequest(http.MethodPost, url, headers, &Body{JSON: data})
+}
+
 // MakePostFormRequest 创建 POST 请求（表单形式）
 func MakePostFormRequest(url string, headers S, form S) (*http.Request, error) {
-	val := _url.Values{}
-	for k, v := range form {
-		val.Set(k, v)
+	if form == nil {
+		form = S{}
 	}
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(val.Encode()))
-	if err != nil {
-		return nil, err
-	}
-	SetHeaders(req, headers)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	return req, nil
+	return MakeBodyRequest(http.MethodPost, url, headers, &Body{Form: form})
 }
 
-// GetClient 获取客户端
+// GetClient 获取客户端（相同的代理和超时共用一个客户端，以复用连接）。
+// 代理地址无效时不会 panic，返回的客户端在发送请求时返回该错误，需要立即得到错误时使用 NewClient
 func GetClient(proxy string, timeout time.Duration) *http.Client {
-	client := &http.Client{}
-	if proxy != "" {
-		proxyURL, err := _url.Parse(proxy)
-		if err != nil {
-			panic(err)
-		}
-		client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
-	}
-	if timeout > 0 {
-		client.Timeout = timeout
+	return GetTLSClient(proxy, timeout, nil)
+}
+
+// GetTLSClient 获取使用指定 TLS 配置的共享客户端，配置无效时返回的客户端每次请求都会返回该错误
+func GetTLSClient(proxy string, timeout time.Duration, tlsOpts *TLSOptions) *http.Client {
+	client, err := sharedClient(proxy, timeout, tlsOpts)
+	if err != nil {
+		return &http.Client{Transport: errTransport{err}}
 	}
 	return client
 }
 
 // Do 发送请求，获取响应
 func Do(cli *http.Client, req *http.Request) (*Response, error) {
-	resp, err := cli.Do(req)
-	if err != nil {
-		return nil, err
+	return DoLimit(cli, req, 0)
+}
+
+// WithTimeout 为上下文设置超时，timeout 不大于 0 时不设置
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
 	}
-	return &Response{resp, bodyBytes}, nil
+	return context.WithCancel(ctx)
 }
 
 func RandInt(min, max int) int {
@@ -107,54 +148,99 @@ func RandInt(min, max int) int {
 
 // Options 请求配置
 type Options struct {
-	Params  S
-	Headers S
-	Data    A
-	Form    S
-	Proxy   string
-	Timeout time.Duration
+	Params      S
+	Headers     S
+	Data        A
+	Form        S
+	JSON        any        // 任意 JSON 请求体
+	XML         any        // XML 请求体
+	Body        []byte     // 原始请求体
+	Reader      io.Reader  // 流式请求体
+	Multipart   *Multipart // 多部分表单（文件上传）
+	ContentType string     // 请求体类型
+	Proxy       string
+	Timeout     time.Duration     // 单次尝试的超时
+	Retry       *RetryPolicy      // 重试策略，为空时不重试
+	MaxBodySize int64             // 响应体最大长度，超过时返回 *BodyTooLargeError，0 表示不限制
+	ProxyConfig *ProxyConfig      // 代理解析配置（环境变量、直连列表、自定义函数），与 Proxy 一起决定每个请求的代理
+	TLS         *TLSOptions       // TLS 配置（根证书、客户端证书、公钥固定等）
+	Auth        Authenticator     // 认证器（Basic、Bearer、Digest、API Key 或自定义）
+	Middlewares []Middleware      // 中间件（缓存、录制回放等），先添加的在外层
+	Transport   http.RoundTripper // 自定义 Transport（例如测试用的 greqstest.Mock），不为空时 Proxy 和 TLS 不再生效
+}
+
+// body 请求体
+func (o *Options) body() *Body {
+	return &Body{
+		Data:        o.Data,
+		Form:        o.Form,
+		JSON:        o.JSON,
+		XML:         o.XML,
+		Raw:         o.Body,
+		Reader:      o.Reader,
+		Multipart:   o.Multipart,
+		ContentType: o.ContentType,
+	}
 }
 
 // Send 发送请求
 func Send(method, url string, opts *Options) (*Response, error) {
-	method = strings.ToUpper(method)
-	if method != "GET" && method != "POST" {
-		return nil, fmt.Errorf("仅支持 GET、POST，不支持 %s", method)
+	return SendContext(context.Background(), method, url, opts)
+}
+
+// SendContext 发送请求（携带上下文，opts.Timeout 通过上下文实现）
+func SendContext(ctx context.Context, method, url string, opts *Options) (*Response, error) {
+	return send(ctx, method, url, opts, false)
+}
+
+// send 发送请求，stream 为 true 时不读取响应体
+func send(ctx context.Context, method, url string, opts *Options, stream bool) (*Response, error) {
+	method, err := CheckMethod(method)
+	if err != nil {
+		return nil, err
 	}
 
 	if opts == nil {
 		opts = &Options{}
 	}
-	cli := GetClient(opts.Proxy, opts.Timeout)
+	body := opts.body()
+	if method == http.MethodPost && body.IsEmpty() {
+		return nil, fmt.Errorf("无效的 POST 请求")
+	}
 
-	if method == "GET" {
-		if opts.Params != nil {
-			url = MakeUrl(url, opts.Params)
-		}
-		req, err := MakeGetRequest(url, opts.Headers)
-		if err != nil {
+	if opts.Params != nil {
+		url = MakeUrl(url, opts.Params)
+	}
+	cli := &http.Client{Transport: opts.Transport}
+	if opts.Transport == nil {
+		if cli, err = sharedClient(opts.Proxy, 0, opts.TLS); err != nil {
 			return nil, err
 		}
-		return Do(cli, req)
-
-	} else {
-		if opts.Data != nil {
-			req, err := MakePostRequest(url, opts.Headers, opts.Data)
-			if err != nil {
-				return nil, err
-			}
-			return Do(cli, req)
-
-		} else if opts.Form != nil {
-			req, err := MakePostFormRequest(url, opts.Headers, opts.Form)
-			if err != nil {
-				return nil, err
-			}
-			return Do(cli, req)
-		} else {
-			return nil, fmt.Errorf("无效的 POST 请求")
+	}
+
+	req, err := MakeBodyRequest(method, url, opts.Headers, body)
+	if err != nil {
+		return nil, err
+	}
+	attempt := func(req *http.Request) (*Response, error) {
+		return fetch(cli, req, opts.Timeout, stream, opts.MaxBodySize)
+	}
+	if opts.ProxyConfig != nil {
+		once := attempt
+		attempt = func(req *http.Request) (*Response, error) {
+			return resolveProxy(opts.ProxyConfig, opts.Proxy, req, once)
 		}
 	}
+	if opts.Auth != nil {
+		once := attempt
+		attempt = func(req *http.Request) (*Response, error) {
+			return authenticate(opts.Auth, req, once)
+		}
+	}
+	handler := func(req *http.Request) (*Response, error) {
+		return Retry(req.Context(), opts.Retry, req, attempt)
+	}
+	return Chain(handler, opts.Middlewares...)(req.WithContext(ctx))
 }
 
 // SendGetRequest 发送 GET 请求
@@ -166,3 +252,18 @@ func SendGetRequest(url string, opts *Options) (*Response, error) {
 func SendPostRequest(url string, opts *Options) (*Response, error) {
 	return Send("POST", url, opts)
 }
+
+// SendPutRequest 发送 PUT 请求
+func SendPutRequest(url string, opts *Options) (*Response, error) {
+	return Send("PUT", url, opts)
+}
+
+// SendPatchRequest 发送 PATCH 请求
+func SendPatchRequest(url string, opts *Options) (*Response, error) {
+	return Send("PATCH", url, opts)
+}
+
+// SendDeleteRequest 发送 DELETE 请求
+func SendDeleteRequest(url string, opts *Options) (*Response, error) {
+	return Send("DELETE", url, opts)
+}
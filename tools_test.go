@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"greqs/log"
 	"testing"
+	"time"
 )
 
 var (
@@ -65,3 +66,15 @@ func TestResponse(t *testing.T) {
 	pjs, _ := resp.PrettyJSONString()
 	fmt.Printf("%s\n\n", pjs)
 }
+
+func TestGetClient_Shared(t *testing.T) {
+	c1 := GetClient("", 3*time.Second)
+	c2 := GetClient("", 3*time.Second)
+	if c1 != c2 {
+		t.Error("same settings should share a client")
+	}
+	c3 := GetClient("", 5*time.Second)
+	if c1 == c3 || c1.Transport != c3.Transport {
+		t.Error("different timeouts should use different clients on the same transport")
+	}
+}
@@ -0,0 +1,336 @@
+package greqs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	_url "net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// CassetteMode 磁带的工作模式
+type CassetteMode int
+
+const (
+	ModeReplay CassetteMode = iota // 只回放，找不到匹配的记录时返回 *NoInteractionError
+	ModeRecord                     // 总是发送请求并记录，覆盖已有的磁带
+	ModeAuto                       // 有匹配的记录时回放，否则发送请求并追加记录
+)
+
+// Redacted 脱敏后的值
+const Redacted = "[REDACTED]"
+
+// DefaultRedactHeaders 默认脱敏的请求头和响应头
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// RecordedRequest 记录的请求
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Url    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	Base64 bool        `json:"base64,omitempty"` // Body 不是 UTF-8 文本时以 base64 编码
+}
+
+// RecordedResponse 记录的响应
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Base64     bool        `json:"base64,omitempty"`
+}
+
+// Interaction 一次请求和响应，磁带文件中每行一条（JSON Lines）
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+// MatchOptions 回放时的匹配规则，方法和网址总是参与比较
+type MatchOptions struct {
+	Body    bool     // 比较请求体
+	Headers []string // 需要比较的请求头
+	// 自定义匹配，在默认规则通过后调用
+	Func func(req *RecordedRequest, rec *RecordedRequest) bool
+}
+
+// RedactOptions 写入磁带前的脱敏规则，匹配时会对请求做同样的处理
+type RedactOptions struct {
+	Headers []string           // 需要脱敏的请求头和响应头，为空时使用 DefaultRedactHeaders
+	Params  []string           // 需要脱敏的查询参数
+	Func    func(*Interaction) // 自定义脱敏，例如替换请求体中的密码，可能对同一条记录调用多次，需要是幂等的
+}
+
+// CassetteOptions 磁带配置
+type CassetteOptions struct {
+	Mode   CassetteMode
+	Match  MatchOptions
+	Redact RedactOptions
+}
+
+// NoInteractionError 回放模式下没有匹配的记录
+type NoInteractionError struct {
+	Method string
+	Url    string
+}
+
+func (e *NoInteractionError) Error() string {
+	return fmt.Sprintf("磁带中没有匹配的记录: %s %s", e.Method, e.Url)
+}
+
+// Cassette 录制与回放请求的磁带，通过 VCR 中间件或 Options.Middlewares 使用
+type Cassette struct {
+	path string
+	opts CassetteOptions
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewCassette 打开磁带，录制模式会清空已有的记录，回放模式要求文件存在
+func NewCassette(path string, opts CassetteOptions) (*Cassette, error) {
+	c := &Cassette{path: path, opts: opts}
+	if opts.Mode == ModeRecord {
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && opts.Mode == ModeAuto {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var i Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &i); err != nil {
+			return nil, fmt.Errorf("解析磁带 %s 第 %d 行失败: %w", path, line, err)
+		}
+		c.interactions = append(c.interactions, &i)
+		c.used = append(c.used, false)
+	}
+	return c, scanner.Err()
+}
+
+// Interactions 磁带中的记录
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Interaction, len(c.interactions))
+	for i, it := range c.interactions {
+		out[i] = *it
+	}
+	return out
+}
+
+// VCR 录制与回放中间件，应该作为第一个中间件，这样回放时不会执行其他中间件（认证、重试等）
+func VCR(c *Cassette) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			return c.roundTrip(req, next)
+		}
+	}
+}
+
+// roundTrip 回放或录制
+func (c *Cassette) roundTrip(req *http.Request, next Handler) (*Response, error) {
+	if err := Rewindable(req); err != nil {
+		return nil, err
+	}
+	recorded, err := c.recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.opts.Mode != ModeRecord {
+		if it := c.find(recorded); it != nil {
+			body, err := decodeRecorded(it.Response.Body, it.Response.Base64)
+			if err != nil {
+				return nil, err
+			}
+			return newResponse(req, it.Response.StatusCode, it.Response.Header.Clone(), body), nil
+		}
+		if c.opts.Mode == ModeReplay {
+			return nil, &NoInteractionError{Method: recorded.Method, Url: recorded.Url}
+		}
+	}
+
+	resp, err := next(req)
+	if err != nil {
+		return resp, err
+	}
+	// 流式响应需要读入内存才能记录
+	if resp.IsStream() {
+		body, err := io.ReadAll(resp.Response.Body)
+		resp.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body, resp.stream, resp.cancel = body, false, nil
+		resp.Response.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	it := &Interaction{Request: *recorded, RecordedAt: time.Now().UTC()}
+	it.Response.StatusCode = resp.StatusCode
+	it.Response.Header = c.redactHeader(resp.Header)
+	it.Response.Body, it.Response.Base64 = encodeRecorded(resp.Body)
+	if c.opts.Redact.Func != nil {
+		c.opts.Redact.Func(it)
+	}
+	if err := c.append(it); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// recordRequest 把请求转换为脱敏后的记录
+func (c *Cassette) recordRequest(req *http.Request) (*RecordedRequest, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	u := *req.URL
+	if len(c.opts.Redact.Params) > 0 {
+		q := u.Query()
+		for _, name := range c.opts.Redact.Params {
+			if q.Has(name) {
+				q.Set(name, Redacted)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	rec := &RecordedRequest{Method: req.Method, Url: u.String(), Header: c.redactHeader(req.Header)}
+	rec.Body, rec.Base64 = encodeRecorded(body)
+	if c.opts.Redact.Func != nil {
+		it := &Interaction{Request: *rec}
+		c.opts.Redact.Func(it)
+		*rec = it.Request
+	}
+	return rec, nil
+}
+
+// redactHeader 复制请求头并脱敏
+func (c *Cassette) redactHeader(h http.Header) http.Header {
+	names := c.opts.Redact.Headers
+	if names == nil {
+		names = DefaultRedactHeaders
+	}
+	out := h.Clone()
+	for _, name := range names {
+		if _, ok := out[http.CanonicalHeaderKey(name)]; ok {
+			out.Set(name, Redacted)
+		}
+	}
+	return out
+}
+
+// find 查找匹配的记录，优先使用还没有回放过的记录，都回放过时重复使用最后一条
+func (c *Cassette) find(req *RecordedRequest) *Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	last := -1
+	for i, it := range c.interactions {
+		if !c.match(req, &it.Request) {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return it
+		}
+		last = i
+	}
+	if last >= 0 {
+		return c.interactions[last]
+	}
+	return nil
+}
+
+// match 请求与记录是否匹配
+func (c *Cassette) match(req, rec *RecordedRequest) bool {
+	if req.Method != rec.Method || !sameUrl(req.Url, rec.Url) {
+		return false
+	}
+	m := c.opts.Match
+	if m.Body && (req.Body != rec.Body || req.Base64 != rec.Base64) {
+		return false
+	}
+	for _, name := range m.Headers {
+		if !slices.Equal(req.Header.Values(name), rec.Header.Values(name)) {
+			return false
+		}
+	}
+	return m.Func == nil || m.Func(req, rec)
+}
+
+// sameUrl 比较网址，查询参数的顺序不影响结果
+func sameUrl(a, b string) bool {
+	ua, err1 := _url.Parse(a)
+	ub, err2 := _url.Parse(b)
+	if err1 != nil || err2 != nil {
+		return a == b
+	}
+	qa, qb := ua.Query(), ub.Query()
+	ua.RawQuery, ub.RawQuery = "", ""
+	return ua.String() == ub.String() && qa.Encode() == qb.Encode()
+}
+
+// append 追加记录并写入文件
+func (c *Cassette) append(it *Interaction) error {
+	line, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	c.interactions = append(c.interactions, it)
+	c.used = append(c.used, true)
+	return nil
+}
+
+// encodeRecorded UTF-8 文本原样保存，其他内容以 base64 保存
+func encodeRecorded(b []byte) (string, bool) {
+	if utf8.Valid(b) {
+		return string(b), false
+	}
+	return base64.StdEncoding.EncodeToString(b), true
+}
+
+func decodeRecorded(s string, isBase64 bool) ([]byte, error) {
+	if isBase64 {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
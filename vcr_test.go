@@ -0,0 +1,197 @@
+package greqs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 回显请求体，并通过 Set-Cookie 返回一个会话，hits 记录收到的请求数
+func newVCRServer(t *testing.T) (*httptest.Server, *int32) {
+	var hits int32
+	echo := newEchoServer()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Set-Cookie", "session=s3cr3t")
+		echo.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(echo.Close)
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestCassette_RecordReplay(t *testing.T) {
+	srv, hits := newVCRServer(t)
+	path := t.TempDir() + "/cassette.jsonl"
+
+	c, err := NewCassette(path, CassetteOptions{
+		Mode:   ModeRecord,
+		Match:  MatchOptions{Body: true},
+		Redact: RedactOptions{Params: []string{"api_key"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorker("", 5*time.Second, nil, nil)
+	w.Use(VCR(c))
+
+	calls := func(w *Worker) []string {
+		var out []string
+		for _, data := range []A{{"id": 1}, {"id": 2}} {
+			resp, err := w.Post(srv.URL+"/items?api_key=k3y&page=1", S{"Authorization": "Bearer t0k3n"}, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, resp.Text()+"|"+resp.Header.Get("X-Query"))
+		}
+		resp, err := w.Get(srv.URL+"/bin", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(out, resp.Header.Get("X-Method"))
+	}
+	recorded := calls(w)
+
+	// 每行一条记录，敏感信息已脱敏
+	b, _ := os.ReadFile(path)
+	if lines := strings.Count(string(b), "\n"); lines != 3 {
+		t.Fatalf("记录了 %d 条", lines)
+	}
+	for _, secret := range []string{"t0k3n", "s3cr3t"} {
+		if strings.Contains(string(b), secret) {
+			t.Fatalf("磁带中包含 %s", secret)
+		}
+	}
+	// 响应头 X-Query 是服务端回显的内容，不在脱敏范围内，只检查请求
+	for _, it := range c.Interactions()[:2] {
+		if it.Request.Url != srv.URL+"/items?api_key=%5BREDACTED%5D&page=1" || it.Request.Header.Get("Authorization") != Redacted {
+			t.Fatalf("请求没有脱敏: %s %v", it.Request.Url, it.Request.Header)
+		}
+	}
+
+	// 服务关闭后回放
+	srv.Close()
+	c, err = NewCassette(path, CassetteOptions{
+		Mode:   ModeReplay,
+		Match:  MatchOptions{Body: true},
+		Redact: RedactOptions{Params: []string{"api_key"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = NewWorker("", 5*time.Second, nil, nil)
+	w.Use(VCR(c))
+	replayed := calls(w)
+	for i := range recorded {
+		if replayed[i] != recorded[i] {
+			t.Errorf("回放 %q, 录制 %q", replayed[i], recorded[i])
+		}
+	}
+	if replayed[1] != `{"id":2}|api_key=k3y&page=1` {
+		t.Errorf("按请求体匹配失败: %q", replayed[1])
+	}
+	if n := atomic.LoadInt32(hits); n != 3 {
+		t.Errorf("hits = %d", n)
+	}
+
+	_, err = w.Post(srv.URL+"/items?api_key=k3y&page=1", nil, A{"id": 3})
+	var ne *NoInteractionError
+	if !errors.As(err, &ne) || ne.Method != "POST" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestCassette_MatchHeaders(t *testing.T) {
+	srv, _ := newVCRServer(t)
+	path := t.TempDir() + "/cassette.jsonl"
+	opts := CassetteOptions{Mode: ModeRecord, Match: MatchOptions{Headers: []string{"Accept"}}}
+
+	c, _ := NewCassette(path, opts)
+	for _, accept := range []string{"text/plain", "application/json"} {
+		r := &Request{Method: "PUT", Url: srv.URL, Headers: S{"Accept": accept}, Body: []byte(accept), Middlewares: []Middleware{VCR(c)}}
+		if _, err := r.Do(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts.Mode = ModeReplay
+	c, _ = NewCassette(path, opts)
+	for _, accept := range []string{"application/json", "text/plain", "application/json"} {
+		resp, err := SendContext(context.Background(), "PUT", srv.URL, &Options{Headers: S{"Accept": accept}, Body: []byte("x"), Middlewares: []Middleware{VCR(c)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Text() != accept {
+			t.Errorf("Accept %s 回放 %q", accept, resp.Text())
+		}
+	}
+	if _, err := SendContext(context.Background(), "PUT", srv.URL, &Options{Headers: S{"Accept": "text/html"}, Body: []byte("x"), Middlewares: []Middleware{VCR(c)}}); err == nil {
+		t.Error("请求头不匹配时应该返回错误")
+	}
+}
+
+func TestCassette_Auto(t *testing.T) {
+	srv, hits := newVCRServer(t)
+	path := t.TempDir() + "/cassette.jsonl"
+
+	for i := 0; i < 2; i++ {
+		c, err := NewCassette(path, CassetteOptions{Mode: ModeAuto})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := NewWorker("", 5*time.Second, nil, nil)
+		w.Use(VCR(c))
+		for j := 0; j < 2; j++ {
+			if _, err := w.Get(srv.URL+"/a", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		resp, err := w.Post(srv.URL+"/b", nil, A{"bin": "\xff"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("status %d", resp.StatusCode)
+		}
+		if got := len(c.Interactions()); got != 2 {
+			t.Fatalf("记录了 %d 条", got)
+		}
+	}
+	// 只有第一轮的第一次 GET 和 POST 访问了服务
+	if n := atomic.LoadInt32(hits); n != 2 {
+		t.Errorf("hits = %d", n)
+	}
+}
+
+func TestCassette_Binary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{0xff, 0x00, 0xfe})
+	}))
+	defer srv.Close()
+	path := t.TempDir() + "/cassette.jsonl"
+
+	c, _ := NewCassette(path, CassetteOptions{Mode: ModeRecord})
+	if _, err := (&Request{Method: "GET", Url: srv.URL, Middlewares: []Middleware{VCR(c)}}).Do(); err != nil {
+		t.Fatal(err)
+	}
+	if it := c.Interactions()[0]; !it.Response.Base64 {
+		t.Fatal("二进制响应体应该以 base64 保存")
+	}
+	c, _ = NewCassette(path, CassetteOptions{Mode: ModeReplay})
+	resp, err := (&Request{Method: "GET", Url: srv.URL, Middlewares: []Middleware{VCR(c)}}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Body) != "\xff\x00\xfe" {
+		t.Fatalf("body %q", resp.Body)
+	}
+	if _, err := NewCassette(t.TempDir()+"/missing.jsonl", CassetteOptions{Mode: ModeReplay}); err == nil {
+		t.Fatal("回放模式下磁带不存在时应该返回错误")
+	}
+}
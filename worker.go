@@ -1,75 +1,527 @@
 package greqs
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	_url "net/url"
+	"sync"
 	"time"
 )
 
 type Worker struct {
 	proxy       string
 	timeout     time.Duration
-	requestHook func(req *http.Request)
+	pool        PoolOptions
+	retry       *RetryPolicy
+	maxBodySize int64             // 响应体最大长度，0 表示不限制
+	limiter     *RateLimiter      // 限流器，作用于每一次尝试
+	proxyPool   *ProxyPool        // 代理池，每一次尝试都会重新选择代理，优先于 proxy
+	connectHdr  S                 // 通过 HTTP 代理建立 CONNECT 隧道时发送的请求头
+	proxyConfig *ProxyConfig      // 代理解析配置（环境变量、直连列表、自定义函数）
+	tls         *TLSOptions       // TLS 配置
+	transport   http.RoundTripper // 自定义 Transport，不为空时代理、连接池和 TLS 配置不再生效
+	auth        Authenticator     // 认证器，作用于每一次尝试
+	baseUrl     *_url.URL         // 基础网址，相对网址会基于它解析
+	headers     S                 // 默认请求头，请求中已有的请求头不会被覆盖
+	jar         http.CookieJar    // Cookie 容器
+	middlewares []Middleware
 	proxyHook   func(cli *http.Client)
+
+	mu     sync.Mutex
+	client *http.Client // 长期持有的客户端，代理、连接池配置变化时重建
 }
 
+// NewWorker 创建 Worker，reqHook 会作为第一个中间件，proxyHook 在每次请求前调用，
+// 参数是长期客户端的副本，修改它（例如替换 Transport）只影响当前请求
 func NewWorker(proxy string, timeout time.Duration, reqHook func(req *http.Request), proxyHook func(cli *http.Client)) *Worker {
-	return &Worker{
-		proxy:       proxy,
-		timeout:     timeout,
-		requestHook: reqHook,
-		proxyHook:   proxyHook,
+	w := &Worker{
+		proxy:     proxy,
+		timeout:   timeout,
+		pool:      DefaultPool,
+		proxyHook: proxyHook,
+	}
+	if reqHook != nil {
+		w.Use(RequestHook(reqHook))
 	}
+	return w
+}
+
+// Use 添加中间件，先添加的在外层
+func (w *Worker) Use(mws ...Middleware) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.middlewares = append(w.middlewares, mws...)
 }
 
 func (w *Worker) GetProxy() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.proxy
 }
 
 func (w *Worker) SetProxy(proxy string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.proxy = proxy
+	w.reset()
 }
 
 func (w *Worker) GetTimeout() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.timeout
 }
 
 func (w *Worker) SetTimeout(timeout time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.timeout = timeout
 }
 
+func (w *Worker) GetPool() PoolOptions {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pool
+}
+
+// SetPool 设置连接池配置
+func (w *Worker) SetPool(pool PoolOptions) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pool = pool
+	w.reset()
+}
+
+func (w *Worker) GetRetry() *RetryPolicy {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.retry
+}
+
+// SetRetry 设置重试策略，nil 表示不重试
+func (w *Worker) SetRetry(policy *RetryPolicy) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.retry = policy
+}
+
+func (w *Worker) GetBaseUrl() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.baseUrl == nil {
+		return ""
+	}
+	return w.baseUrl.String()
+}
+
+// SetBaseUrl 设置基础网址，之后可以使用相对网址发送请求
+func (w *Worker) SetBaseUrl(baseUrl string) error {
+	var u *_url.URL
+	if baseUrl != "" {
+		var err error
+		if u, err = _url.Parse(baseUrl); err != nil {
+			return fmt.Errorf("解析基础网址失败: %w", err)
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.baseUrl = u
+	return nil
+}
+
+func (w *Worker) GetHeaders() S {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.headers
+}
+
+// SetHeaders 设置默认请求头
+func (w *Worker) SetHeaders(headers S) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.headers = headers
+}
+
+func (w *Worker) GetJar() http.CookieJar {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.jar
+}
+
+// SetJar 设置 Cookie 容器，nil 表示不保存 Cookie
+func (w *Worker) SetJar(jar http.CookieJar) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.jar = jar
+	w.reset()
+}
+
+func (w *Worker) GetMaxBodySize() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.maxBodySize
+}
+
+// SetMaxBodySize 设置响应体最大长度，超过时返回 *BodyTooLargeError，0 表示不限制（对流式模式无效）
+func (w *Worker) SetMaxBodySize(size int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.maxBodySize = size
+}
+
+func (w *Worker) GetRateLimiter() *RateLimiter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.limiter
+}
+
+// SetRateLimiter 设置限流器，nil 表示不限流
+func (w *Worker) SetRateLimiter(limiter *RateLimiter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.limiter = limiter
+}
+
+func (w *Worker) GetProxyPool() *ProxyPool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.proxyPool
+}
+
+// SetProxyPool 设置代理池，nil 表示不使用代理池
+func (w *Worker) SetProxyPool(pool *ProxyPool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.proxyPool = pool
+}
+
+func (w *Worker) GetProxyConnectHeaders() S {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.connectHdr
+}
+
+// SetProxyConnectHeaders 设置通过 HTTP 代理访问 https 网址时 CONNECT 请求携带的请求头
+func (w *Worker) SetProxyConnectHeaders(headers S) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.connectHdr = headers
+	w.reset()
+}
+
+func (w *Worker) GetProxyConfig() *ProxyConfig {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.proxyConfig
+}
+
+// SetProxyConfig 设置代理解析配置，与 proxy 一起决定每个请求的代理，代理池优先于它
+func (w *Worker) SetProxyConfig(cfg *ProxyConfig) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.proxyConfig = cfg
+}
+
+func (w *Worker) GetTLS() *TLSOptions {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.tls
+}
+
+func (w *Worker) GetAuth() Authenticator {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.auth
+}
+
+// SetAuth 设置认证器，每一次尝试（包括重试）发送前都会重新认证
+func (w *Worker) SetAuth(auth Authenticator) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.auth = auth
+}
+
+// SetTLS 设置 TLS 配置，配置无效时在下一次请求时返回错误
+func (w *Worker) SetTLS(opts *TLSOptions) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tls = opts
+	w.reset()
+}
+
+func (w *Worker) GetTransport() http.RoundTripper {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.transport
+}
+
+// SetTransport 设置自定义 Transport（例如 greqstest.Mock），为 nil 时恢复默认
+func (w *Worker) SetTransport(rt http.RoundTripper) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.transport = rt
+	w.reset()
+}
+
+// Client 获取 Worker 持有的客户端，首次调用或配置变化后才会创建
+func (w *Worker) Client() (*http.Client, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.client != nil {
+		return w.client, nil
+	}
+	if w.transport != nil {
+		w.client = &http.Client{Transport: w.transport, Jar: w.jar}
+		return w.client, nil
+	}
+	transport, err := NewTransport(w.proxy, w.pool)
+	if err != nil {
+		return nil, err
+	}
+	if w.tls != nil {
+		if transport.TLSClientConfig, err = w.tls.Config(); err != nil {
+			return nil, err
+		}
+	}
+	if len(w.connectHdr) > 0 {
+		transport.ProxyConnectHeader = http.Header{}
+		for key, val := range w.connectHdr {
+			transport.ProxyConnectHeader.Set(key, val)
+		}
+	}
+	w.client = &http.Client{Transport: transport, Jar: w.jar}
+	return w.client, nil
+}
+
+// CloseIdleConnections 关闭连接池中的空闲连接
+func (w *Worker) CloseIdleConnections() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.client != nil {
+		w.client.CloseIdleConnections()
+	}
+}
+
+// reset 丢弃当前客户端（调用方需持有锁）
+func (w *Worker) reset() {
+	if w.client != nil {
+		w.client.CloseIdleConnections()
+		w.client = nil
+	}
+}
+
 func (w *Worker) Get(url string, headers S) (*Response, error) {
+	return w.GetCtx(context.Background(), url, headers)
+}
+
+func (w *Worker) GetCtx(ctx context.Context, url string, headers S) (*Response, error) {
 	req, err := MakeGetRequest(url, headers)
 	if err != nil {
 		return nil, err
 	}
-	return w.Go(req)
+	return w.GoContext(ctx, req)
 }
 
 func (w *Worker) Post(url string, headers S, data A) (*Response, error) {
+	return w.PostCtx(context.Background(), url, headers, data)
+}
+
+func (w *Worker) PostCtx(ctx context.Context, url string, headers S, data A) (*Response, error) {
 	req, err := MakePostRequest(url, headers, data)
 	if err != nil {
 		return nil, err
 	}
-	return w.Go(req)
+	return w.GoContext(ctx, req)
 }
 
 func (w *Worker) PostForm(url string, headers S, form S) (*Response, error) {
+	return w.PostFormCtx(context.Background(), url, headers, form)
+}
+
+func (w *Worker) PostFormCtx(ctx context.Context, url string, headers S, form S) (*Response, error) {
 	req, err := MakePostFormRequest(url, headers, form)
 	if err != nil {
 		return nil, err
 	}
-	return w.Go(req)
+	return w.GoContext(ctx, req)
+}
+
+func (w *Worker) Put(url string, headers S, data A) (*Response, error) {
+	return w.Send("PUT", url, headers, data, nil)
+}
+
+func (w *Worker) Patch(url string, headers S, data A) (*Response, error) {
+	return w.Send("PATCH", url, headers, data, nil)
+}
+
+func (w *Worker) Delete(url string, headers S) (*Response, error) {
+	return w.Send("DELETE", url, headers, nil, nil)
+}
+
+func (w *Worker) Head(url string, headers S) (*Response, error) {
+	return w.Send("HEAD", url, headers, nil, nil)
+}
+
+func (w *Worker) Options(url string, headers S) (*Response, error) {
+	return w.Send("OPTIONS", url, headers, nil, nil)
+}
+
+// Send 发送任意方法的请求（data 使用 JSON 形式，form 使用表单形式）
+func (w *Worker) Send(method, url string, headers S, data A, form S) (*Response, error) {
+	return w.SendContext(context.Background(), method, url, headers, data, form)
+}
+
+// SendContext 发送任意方法的请求（携带上下文）
+func (w *Worker) SendContext(ctx context.Context, method, url string, headers S, data A, form S) (*Response, error) {
+	return w.SendBody(ctx, method, url, headers, &Body{Data: data, Form: form})
+}
+
+// SendBody 发送任意方法、任意请求体的请求
+func (w *Worker) SendBody(ctx context.Context, method, url string, headers S, body *Body) (*Response, error) {
+	req, err := MakeBodyRequest(method, url, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	return w.GoContext(ctx, req)
+}
+
+// Do 使用 Worker 执行 Request。Request 中设置了的代理、代理解析配置、超时、重试、认证和响应体上限覆盖 Worker 的配置，
+// 中间件追加在 Worker 的中间件之后；TLS 配置属于 Worker 的客户端，不能按请求设置，设置时返回错误
+func (w *Worker) Do(ctx context.Context, r *Request) (*Response, error) {
+	if r.TLS != nil {
+		return nil, errors.New("Worker.Do 不支持按请求设置 TLS，请使用 Worker.SetTLS")
+	}
+	if r.Transport != nil {
+		return nil, errors.New("Worker.Do 不支持按请求设置 Transport，请使用 Worker.SetTransport")
+	}
+	req, err := r.HTTPRequest()
+	if err != nil {
+		return nil, err
+	}
+	return w.do(ctx, req, fetchBuffered, r.Options())
 }
 
 func (w *Worker) Go(req *http.Request) (*Response, error) {
-	if w.requestHook != nil {
-		w.requestHook(req)
+	return w.GoContext(req.Context(), req)
+}
+
+// GoContext 使用 ctx 发送请求，请求依次经过中间件后发送，
+// Worker 的超时通过上下文实现，作用于每一次尝试
+func (w *Worker) GoContext(ctx context.Context, req *http.Request) (*Response, error) {
+	return w.do(ctx, req, fetchBuffered, nil)
+}
+
+// fetchMode 响应的读取方式
+type fetchMode int
+
+const (
+	fetchBuffered fetchMode = iota // 读取完整的响应体
+	fetchStream                    // 流式读取，超时覆盖整个读取过程
+	fetchDownload                  // 下载使用的流式读取，不重试（由下载自行继续），超时作为空闲超时
+)
+
+// do 发送请求，按 mode 读取响应体。opts 不为空时其中设置了的字段覆盖 Worker 的配置（见 Do）。
+// 请求会先复制一份，不会修改调用方的请求
+func (w *Worker) do(ctx context.Context, req *http.Request, mode fetchMode, opts *Options) (*Response, error) {
+	cli, err := w.Client()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(ctx)
+	w.prepare(req)
+
+	w.mu.Lock()
+	timeout, retry, maxBodySize, limiter, mws := w.timeout, w.retry, w.maxBodySize, w.limiter, w.middlewares
+	proxy, proxyPool, proxyConfig, auth := w.proxy, w.proxyPool, w.proxyConfig, w.auth
+	proxyHook := w.proxyHook
+	w.mu.Unlock()
+
+	if opts != nil {
+		if opts.Timeout > 0 {
+			timeout = opts.Timeout
+		}
+		if opts.Retry != nil {
+			retry = opts.Retry
+		}
+		if opts.MaxBodySize > 0 {
+			maxBodySize = opts.MaxBodySize
+		}
+		if opts.Auth != nil {
+			auth = opts.Auth
+		}
+		if opts.ProxyConfig != nil {
+			proxyConfig, proxyPool = opts.ProxyConfig, nil
+		}
+		if opts.Proxy != "" {
+			// 通过上下文指定代理，不影响 Worker 的客户端
+			proxy, proxyPool = opts.Proxy, nil
+			if proxyConfig == nil {
+				proxyConfig = &ProxyConfig{}
+			}
+		}
+		mws = append(mws[:len(mws):len(mws)], opts.Middlewares...)
+	}
+
+	if proxyHook != nil {
+		c := *cli
+		proxyHook(&c)
+		cli = &c
+	}
+
+	attempt := func(req *http.Request) (*Response, error) {
+		return fetch(cli, req, timeout, mode == fetchStream, maxBodySize)
 	}
+	if mode == fetchDownload {
+		retry = nil
+		attempt = func(req *http.Request) (*Response, error) {
+			return fetchIdle(cli, req, timeout)
+		}
+	}
+	if proxyConfig != nil && proxyPool == nil {
+		once := attempt
+		attempt = func(req *http.Request) (*Response, error) {
+			return resolveProxy(proxyConfig, proxy, req, once)
+		}
+	}
+	if proxyPool != nil {
+		once := attempt
+		attempt = func(req *http.Request) (*Response, error) {
+			return proxyPool.use(req, once)
+		}
+	}
+	if limiter != nil {
+		once := attempt
+		attempt = func(req *http.Request) (*Response, error) {
+			return limiter.limit(req, once)
+		}
+	}
+	if auth != nil {
+		once := attempt
+		attempt = func(req *http.Request) (*Response, error) {
+			return authenticate(auth, req, once)
+		}
+	}
+	send := func(req *http.Request) (*Response, error) {
+		return Retry(req.Context(), retry, req, attempt)
+	}
+	return Chain(send, mws...)(req)
+}
 
-	cli := GetClient(w.proxy, w.timeout)
+// prepare 补全相对网址和默认请求头
+func (w *Worker) prepare(req *http.Request) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-	if w.proxyHook != nil {
-		w.proxyHook(cli)
+	if w.baseUrl != nil && !req.URL.IsAbs() {
+		req.URL = w.baseUrl.ResolveReference(req.URL)
+		req.Host = req.URL.Host
+	}
+	for key, val := range w.headers {
+		if req.Header.Get(key) == "" {
+			req.Header.Set(key, val)
+		}
 	}
-	return Do(cli, req)
 }
@@ -1,9 +1,15 @@
 package greqs
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	_url "net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -33,3 +39,179 @@ func TestWorker_Get(t *testing.T) {
 	fmt.Printf("%+v\n", resp.Request.Header)
 	fmt.Println(resp.Request.Header.Get("Name"))
 }
+
+func TestWorker_Methods(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	worker := NewWorker("", 5*time.Second, m1, nil)
+	for _, method := range []string{"PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"} {
+		resp, err := worker.Send(method, srv.URL, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("worker.Send %s err: %v", method, err)
+		}
+		if got := resp.Header.Get("X-Method"); got != method {
+			t.Errorf("method = %s, want %s", got, method)
+		}
+	}
+
+	resp, err := worker.Put(srv.URL, nil, A{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("X-Content-Type") != "application/json" || resp.Text() != `{"id":1}` {
+		t.Errorf("unexpected PUT response: %s", resp.Text())
+	}
+}
+
+// 统计服务端建立的新连接数
+func newCountingServer() (*httptest.Server, *int64) {
+	var conns int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	srv.Start()
+	return srv, &conns
+}
+
+func TestWorker_ConnReuse(t *testing.T) {
+	srv, conns := newCountingServer()
+	defer srv.Close()
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	for i := 0; i < 20; i++ {
+		if _, err := worker.Get(srv.URL, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt64(conns); n != 1 {
+		t.Errorf("new conns = %d, want 1", n)
+	}
+
+	worker.SetPool(PoolOptions{MaxIdleConnsPerHost: 1, IdleConnTimeout: time.Second})
+	if _, err := worker.Get(srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(conns); n != 2 {
+		t.Errorf("new conns after SetPool = %d, want 2", n)
+	}
+}
+
+func BenchmarkWorker_Get(b *testing.B) {
+	srv, conns := newCountingServer()
+	defer srv.Close()
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := worker.Get(srv.URL, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(conns)), "conns")
+}
+
+func BenchmarkGetClient_PerCall(b *testing.B) {
+	srv, conns := newCountingServer()
+	defer srv.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// 每次新建 Transport，模拟不复用连接的情况
+		cli := &http.Client{Transport: &http.Transport{}}
+		req, _ := MakeGetRequest(srv.URL, nil)
+		if _, err := Do(cli, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(conns)), "conns")
+}
+
+func TestWorker_GoContext(t *testing.T) {
+	srv := newSlowServer(time.Second)
+	defer srv.Close()
+
+	worker := NewWorker("", 50*time.Millisecond, nil, nil)
+	if _, err := worker.Get(srv.URL, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+
+	worker.SetTimeout(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := worker.GetCtx(ctx, srv.URL, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWorker_ProxyHook(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	// proxyHook 每次请求都会调用，修改的是客户端副本
+	var calls int64
+	worker := NewWorker("", 5*time.Second, nil, func(cli *http.Client) {
+		if atomic.AddInt64(&calls, 1) == 2 {
+			cli.Transport = errTransport{errors.New("hooked")}
+		}
+	})
+	for i, want := range []string{"", "hooked", ""} {
+		_, err := worker.Get(srv.URL, nil)
+		if want == "" && err != nil || want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("%d: err = %v", i, err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("proxyHook 调用了 %d 次", calls)
+	}
+	cli, _ := worker.Client()
+	if _, ok := cli.Transport.(errTransport); ok {
+		t.Error("proxyHook 不应修改长期客户端")
+	}
+}
+
+func TestWorker_Do(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+	proxy := newProxyServer("p1")
+	defer proxy.Close()
+
+	worker := NewWorker("", 5*time.Second, nil, nil)
+	worker.SetHeaders(S{"X-Default": "1"})
+
+	// 默认请求头加在请求的副本上，不修改调用方的请求
+	req, _ := MakeGetRequest(srv.URL, nil)
+	if _, err := worker.Go(req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("X-Default") != "" {
+		t.Error("调用方的请求被修改")
+	}
+
+	// Request 中的代理、认证和中间件生效
+	var seen string
+	resp, err := worker.Do(context.Background(), &Request{
+		Method: "GET",
+		Url:    "http://example.com/a",
+		Proxy:  proxy.URL,
+		Auth:   &BearerAuth{Token: "t"},
+		Middlewares: []Middleware{RequestHook(func(req *http.Request) {
+			seen = req.Header.Get("X-Default")
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "p1 http://example.com/a" || resp.Request.Header.Get("Authorization") != "Bearer t" || seen != "1" {
+		t.Errorf("%q %v %q", resp.Text(), resp.Request.Header, seen)
+	}
+
+	if _, err := worker.Do(context.Background(), &Request{Method: "GET", Url: srv.URL, TLS: &TLSOptions{}}); err == nil {
+		t.Error("按请求设置 TLS 应该返回错误")
+	}
+}